@@ -0,0 +1,102 @@
+// Package augment makes changed copies of the camera images in a tub, so a
+// model trained on them copes with other light and learns to turn both ways.
+package augment
+
+import (
+	"image"
+	"image/draw"
+	"math/rand"
+)
+
+// Options chooses the changes made to each image.
+type Options struct {
+	// Brightness is the most the brightness is changed by, as a fraction.
+	Brightness float64
+
+	// Shadow darkens a band across the image half of the time.
+	Shadow bool
+
+	// Flip mirrors the image half of the time, and negates the steering.
+	Flip bool
+}
+
+// Apply returns a changed copy of img, and the steering that goes with it.
+func Apply(img image.Image, steering float64, o Options, rnd *rand.Rand) (*image.RGBA, float64) {
+	out := toRGBA(img)
+	if o.Brightness > 0 {
+		Brightness(out, 1+o.Brightness*(2*rnd.Float64()-1))
+	}
+	if o.Shadow && rnd.Intn(2) == 0 {
+		b := out.Bounds()
+		top := [2]int{rnd.Intn(b.Dx()), rnd.Intn(b.Dx())}
+		bottom := [2]int{rnd.Intn(b.Dx()), rnd.Intn(b.Dx())}
+		Shadow(out, top, bottom, 0.4+0.4*rnd.Float64())
+	}
+	if o.Flip && rnd.Intn(2) == 0 {
+		out = Flip(out)
+		steering = -steering
+	}
+	return out, steering
+}
+
+// Brightness multiplies the colours of img by factor.
+func Brightness(img *image.RGBA, factor float64) {
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(img.Rect.Min.X, y):img.PixOffset(img.Rect.Max.X, y)]
+		for i := range row {
+			if i%4 == 3 {
+				continue
+			}
+			row[i] = clamp(float64(row[i]) * factor)
+		}
+	}
+}
+
+// Shadow darkens img by factor in the band between two lines from the top to
+// the bottom, the first from top[0] to bottom[0] and the second from top[1]
+// to bottom[1], as a shadow falling across the track.
+func Shadow(img *image.RGBA, top, bottom [2]int, factor float64) {
+	b := img.Rect
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		f := float64(y-b.Min.Y) / float64(b.Dy())
+		x0 := b.Min.X + int(float64(top[0])+f*float64(bottom[0]-top[0]))
+		x1 := b.Min.X + int(float64(top[1])+f*float64(bottom[1]-top[1]))
+		if x0 > x1 {
+			x0, x1 = x1, x0
+		}
+		for x := x0; x < x1 && x < b.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			for c := 0; c < 3; c++ {
+				img.Pix[i+c] = clamp(float64(img.Pix[i+c]) * factor)
+			}
+		}
+	}
+}
+
+// Flip returns img mirrored left to right.
+func Flip(img *image.RGBA) *image.RGBA {
+	b := img.Rect
+	out := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.SetRGBA(b.Max.X-1-(x-b.Min.X), y, img.RGBAAt(x, y))
+		}
+	}
+	return out
+}
+
+func toRGBA(img image.Image) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	draw.Draw(out, out.Rect, img, img.Bounds().Min, draw.Src)
+	return out
+}
+
+func clamp(v float64) uint8 {
+	if v > 255 {
+		return 255
+	}
+	if v < 0 {
+		return 0
+	}
+	return uint8(v)
+}
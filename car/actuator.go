@@ -0,0 +1,72 @@
+package car
+
+import (
+	"gobot.io/x/gobot"
+
+	"github.com/hybridgroup/gophercar/config"
+	"github.com/hybridgroup/gophercar/device"
+)
+
+// Actuator sets the steering and throttle of a car, both from -1 to 1.
+// Steering is negative to the left, and throttle is negative in reverse.
+type Actuator interface {
+	SetSteering(steering float64) error
+	SetThrottle(throttle float64) error
+}
+
+// PCA9685 drives the steering servo and the ESC from a PCA9685 PWM controller.
+type PCA9685 struct {
+	PWM    *device.PWM
+	Config config.Car
+}
+
+// NewPCA9685 returns a PCA9685 Actuator using the channels and pulses in cfg.
+func NewPCA9685(pwm *device.PWM, cfg config.Car) *PCA9685 {
+	return &PCA9685{PWM: pwm, Config: cfg}
+}
+
+// Init sets the PWM frequency and sends the stopped pulse to the ESC, which it
+// needs to see before it will drive.
+func (p *PCA9685) Init() error {
+	if err := p.PWM.SetPWMFreq(p.Config.PWMFrequency); err != nil {
+		return err
+	}
+	return p.SetThrottle(0)
+}
+
+// SetSteering sets the steering servo.
+func (p *PCA9685) SetSteering(steering float64) error {
+	return p.PWM.SetPWM(p.Config.SteeringChannel, 0, uint16(SteeringPulse(p.Config, steering)))
+}
+
+// SetThrottle sets the ESC.
+func (p *PCA9685) SetThrottle(throttle float64) error {
+	return p.PWM.SetPWM(p.Config.ThrottleChannel, 0, uint16(ThrottlePulse(p.Config, throttle)))
+}
+
+// SteeringPulse adjusts the steering from -1.0 (hard left) <-> 1.0 (hard
+// right) to the pwm pulse for the servo.
+func SteeringPulse(cfg config.Car, steering float64) int {
+	steering = clamp(steering, -1, 1)
+	return int(gobot.Rescale(steering, -1, 1, float64(cfg.SteeringLeftPulse), float64(cfg.SteeringRightPulse)))
+}
+
+// ThrottlePulse adjusts the throttle from -1.0 (hard back) <-> 1.0 (hard
+// forward) to the pwm pulse for the ESC.
+func ThrottlePulse(cfg config.Car, throttle float64) int {
+	throttle = clamp(throttle, -1, 1)
+	if throttle > 0 {
+		return int(gobot.Rescale(throttle, 0, 1, float64(cfg.ThrottleStoppedPulse), float64(cfg.ThrottleForwardPulse)))
+	}
+	return int(gobot.Rescale(throttle, -1, 0, float64(cfg.ThrottleReversePulse), float64(cfg.ThrottleStoppedPulse)))
+}
+
+func clamp(v, min, max float64) float64 {
+	switch {
+	case v < min:
+		return min
+	case v > max:
+		return max
+	}
+	return v
+}
@@ -0,0 +1,147 @@
+package car
+
+import (
+	"image"
+	"time"
+
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/detect"
+	"github.com/hybridgroup/gophercar/metrics"
+	"github.com/hybridgroup/gophercar/pilot"
+	"github.com/hybridgroup/gophercar/runlog"
+	"github.com/hybridgroup/gophercar/telemetry"
+	"github.com/hybridgroup/gophercar/vision"
+)
+
+// capture runs the vision pipeline until the camera has no more frames.
+func (c *Car) capture() {
+	defer close(c.captured)
+	c.pipeline = vision.NewPipeline(c.Camera, c.handleFrame, c.handleStream)
+	c.pipeline.StreamInterval = c.StreamInterval
+	c.pipeline.Raw = c.Raw
+	c.pipeline.Timings = c.Timings
+	c.pipeline.Run()
+
+	c.Log.Warn("camera closed", nil)
+}
+
+// handleFrame runs the pilot on a frame in the pipeline vision worker, records
+// it, and returns the annotated frame to stream.
+func (c *Car) handleFrame(img gocv.Mat, captured time.Time) gocv.Mat {
+	start := time.Now()
+
+	c.recordFrame(img, captured)
+	c.detect(img, captured)
+
+	found := true
+	annotated := c.annotated
+	if c.Pilot != nil {
+		out := c.Pilot.Run(img)
+		found = out.OK
+		if out.OK {
+			c.mu.Lock()
+			c.pilot = controls{steering: out.Steering, throttle: out.Throttle}
+			c.steeringFrame = captured
+			c.mu.Unlock()
+		}
+		if a, ok := c.Pilot.(pilot.Annotator); ok {
+			annotated = a.Annotated()
+		} else {
+			img.CopyTo(&c.annotated)
+		}
+	} else {
+		img.CopyTo(&c.annotated)
+	}
+
+	if dt := start.Sub(c.lastFrame).Seconds(); dt > 0 {
+		c.fps = 0.9*c.fps + 0.1/dt
+	}
+	c.lastFrame = start
+
+	frames := c.pipeline.Frames()
+	c.Telemetry.Update(func(d *telemetry.Data) {
+		d.LineFound = found
+		if !found {
+			d.LineLost++
+		}
+		d.FPS = c.fps
+		d.Frames = frames
+	})
+
+	if len(c.detections) > 0 {
+		// a pilot may annotate only the bottom of the frame
+		detect.Draw(&annotated, c.detections, image.Pt(0, annotated.Rows()-img.Rows()))
+	}
+	if c.HUD {
+		vision.DrawHUD(&annotated, c.Telemetry.Get())
+	}
+
+	if c.stages != nil {
+		c.stages.Finish(annotated)
+	}
+
+	return annotated
+}
+
+// detect looks for objects in one of every DetectEvery frames, and updates the
+// Behaviour with what it found.
+func (c *Car) detect(img gocv.Mat, captured time.Time) {
+	if c.Detector == nil {
+		return
+	}
+	if c.detectSkip > 0 {
+		c.detectSkip--
+		return
+	}
+	c.detectSkip = c.DetectEvery - 1
+
+	start := time.Now()
+	c.detections = c.Detector.Detect(img)
+	c.Timings.Timer(metrics.StageDetect).Since(start)
+	if c.Behaviour == nil {
+		return
+	}
+
+	seen := detect.Sightings(c.detections, image.Pt(img.Cols(), img.Rows()))
+	for _, e := range c.Behaviour.Update(seen, captured) {
+		if e.Started {
+			c.Log.Info("behaviour started", runlog.Fields{"event": e})
+		} else {
+			c.Log.Info("behaviour ended", runlog.Fields{"event": e})
+		}
+	}
+	status := c.Behaviour.Status()
+	c.Telemetry.Update(func(d *telemetry.Data) { d.Behaviour = status })
+}
+
+// handleStream encodes frames for the MJPEG streams in the pipeline encoder.
+func (c *Car) handleStream(annotated, raw gocv.Mat) {
+	buf, err := gocv.IMEncode(".jpg", annotated)
+	if err != nil {
+		c.Log.Limit("encode", time.Second, runlog.Error, "encoding stream failed", runlog.Fields{"error": err.Error()})
+		return
+	}
+	c.stream.UpdateJPEG(buf)
+	c.lastJPEG.Store(buf)
+
+	if c.Raw {
+		buf, err := gocv.IMEncode(".jpg", raw)
+		if err != nil {
+			return
+		}
+		c.rawStream.UpdateJPEG(buf)
+	}
+}
+
+// Stage returns the function to pass the stages of the vision processing to,
+// or nil when not in Debug mode.
+func (c *Car) Stage() vision.StageFunc {
+	if !c.Debug {
+		return nil
+	}
+	if c.stages == nil {
+		c.stages = vision.NewStageStreams()
+	}
+	return c.stages.Add
+}
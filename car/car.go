@@ -0,0 +1,426 @@
+// Package car runs a car: the drive loop that reads the IMU and sends the
+// steering and throttle from the driver or the pilot to the actuators, the
+// camera pipeline, recording to a tub, and the web server. The hardware is
+// passed in, so the same Car can drive a real car or a simulator.
+package car
+
+import (
+	"image"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hybridgroup/mjpeg"
+	"gobot.io/x/gobot"
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/config"
+	"github.com/hybridgroup/gophercar/control"
+	"github.com/hybridgroup/gophercar/detect"
+	"github.com/hybridgroup/gophercar/device"
+	"github.com/hybridgroup/gophercar/distance"
+	"github.com/hybridgroup/gophercar/imu"
+	"github.com/hybridgroup/gophercar/metrics"
+	"github.com/hybridgroup/gophercar/odometry"
+	"github.com/hybridgroup/gophercar/pilot"
+	"github.com/hybridgroup/gophercar/runlog"
+	"github.com/hybridgroup/gophercar/telemetry"
+	"github.com/hybridgroup/gophercar/tub"
+	"github.com/hybridgroup/gophercar/vision"
+)
+
+// RecordSize is the size of the camera images saved to a tub, the same as the
+// Donkeycar.
+var RecordSize = image.Pt(160, 120)
+
+// Car is a running car.
+type Car struct {
+	Config   *config.Config
+	Actuator Actuator
+
+	// Orientation is the IMU, and Camera where frames are read from. Either
+	// may be nil if the car does not have one.
+	Orientation *imu.IMU
+	Camera      vision.FrameSource
+
+	// Pilot drives the car in the local modes.
+	Pilot pilot.Pilot
+
+	// Detector finds objects in one of every DetectEvery frames, and
+	// Behaviour decides whether to stop, wait or slow down for them in the
+	// local modes. Either may be nil.
+	Detector    detect.Detector
+	DetectEvery int
+	Behaviour   *control.Behaviour
+
+	// Odometer measures the speed and distance driven, and is updated by the
+	// drive loop. Cruise, if set, drives at its target speed measured by the
+	// Odometer instead of the pilot throttle in local mode. Either may be nil.
+	Odometer *odometry.Odometer
+	Cruise   *control.Cruise
+
+	// Range looks ahead for obstacles, and is read every RangeInterval for
+	// the Collision brake, in all drive modes. It may be nil.
+	Range         distance.Sensor
+	RangeInterval time.Duration
+	Collision     *control.Collision
+
+	// Devices are the monitored I2C devices. Add them with AddDevice.
+	Devices device.Devices
+
+	Telemetry  *telemetry.Telemetry
+	Timings    *metrics.Timings
+	DriveLoop  *metrics.Loop
+	Crash      *imu.CrashDetector
+	Stabilizer *control.Stabilizer
+	Log        *runlog.Logger
+
+	// HUD draws the telemetry over the video stream, Raw also streams the
+	// camera without annotations at /raw, and Debug streams each stage of the
+	// vision processing at /stage/<name>. Metrics serves Prometheus metrics
+	// at /metrics.
+	HUD     bool
+	Raw     bool
+	Debug   bool
+	Metrics bool
+
+	// StreamInterval is the shortest time between frames of the video
+	// streams.
+	StreamInterval time.Duration
+
+	// SnapshotDir is where a snapshot of the camera is saved on a crash.
+	SnapshotDir string
+
+	mu            sync.Mutex
+	mode          control.Mode
+	user, pilot   controls
+	steeringFrame time.Time
+	actuatedFrame time.Time
+
+	// recording
+	tub       *tub.Tub
+	recording bool
+	records   chan record
+	recordImg gocv.Mat
+
+	pipeline  *vision.Pipeline
+	captured  chan struct{}
+	stream    *mjpeg.Stream
+	rawStream *mjpeg.Stream
+	stages    *vision.StageStreams
+	annotated gocv.Mat
+	lastJPEG  atomic.Value
+	fps       float64
+	lastFrame time.Time
+
+	detections []detect.Detection
+	detectSkip int
+}
+
+type controls struct {
+	steering, throttle float64
+}
+
+type record struct {
+	r    tub.Record
+	jpeg []byte
+}
+
+// New returns a Car in user mode that drives with act.
+func New(cfg *config.Config, act Actuator, log *runlog.Logger) *Car {
+	timings := metrics.NewTimings(metrics.DefaultWindow)
+	c := &Car{
+		Config:         cfg,
+		Actuator:       act,
+		Telemetry:      telemetry.New(),
+		Timings:        timings,
+		DriveLoop:      metrics.NewLoop("drive", time.Duration(cfg.Car.LoopInterval), timings.Timer(metrics.StageDrive), log),
+		Crash:          imu.NewCrashDetector(),
+		Stabilizer:     control.NewStabilizer(cfg.Stabilizer),
+		RangeInterval:  50 * time.Millisecond,
+		Collision:      control.NewCollision(cfg.Collision),
+		Log:            log,
+		HUD:            true,
+		Metrics:        true,
+		StreamInterval: 100 * time.Millisecond,
+		DetectEvery:    1,
+		SnapshotDir:    "/tmp",
+		mode:           control.User,
+		stream:         mjpeg.NewStream(),
+		rawStream:      mjpeg.NewStream(),
+		recordImg:      gocv.NewMat(),
+		annotated:      gocv.NewMat(),
+	}
+	if cfg.Car.LoopInterval > 0 {
+		c.Stabilizer.MaxInterval = 4 * time.Duration(cfg.Car.LoopInterval)
+	}
+	c.Telemetry.Update(func(d *telemetry.Data) {
+		d.Mode = string(c.mode)
+		d.Address = cfg.Car.Address
+	})
+	return c
+}
+
+// AddDevice monitors an I2C device, stopping the car if it keeps failing.
+func (c *Car) AddDevice(m *device.Monitor) {
+	m.OnFailure = c.deviceFailed
+	c.Devices = append(c.Devices, m)
+}
+
+// Start starts the drive loop and, if the car has a camera, the vision
+// pipeline. Calibrate the IMU and initialise the actuators before calling it.
+func (c *Car) Start() {
+	gobot.Every(time.Duration(c.Config.Car.LoopInterval), func() {
+		c.DriveLoop.Run(c.step)
+	})
+
+	gobot.Every(1*time.Second, func() {
+		if temp, err := telemetry.CPUTemperature(); err == nil {
+			c.Telemetry.Update(func(d *telemetry.Data) { d.CPUTemperature = temp })
+		}
+		c.Log.Debug("telemetry", runlog.Fields{"telemetry": c.Telemetry.Get()})
+	})
+
+	if c.Range != nil {
+		gobot.Every(c.RangeInterval, c.updateRange)
+	}
+
+	if c.Camera != nil {
+		c.captured = make(chan struct{})
+		go c.capture()
+	}
+}
+
+// WaitCamera waits for the vision pipeline to drain once the camera has no
+// more frames, so that the pilot can be closed. It returns at once if the
+// vision pipeline was not started.
+func (c *Car) WaitCamera() {
+	if c.captured != nil {
+		<-c.captured
+	}
+}
+
+// Close stops recording and frees the resources of the car.
+func (c *Car) Close() error {
+	c.StopRecording()
+	if c.stages != nil {
+		c.stages.Close()
+	}
+	c.recordImg.Close()
+	return c.annotated.Close()
+}
+
+// Mode returns the drive mode.
+func (c *Car) Mode() control.Mode {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mode
+}
+
+// SetMode changes the drive mode.
+func (c *Car) SetMode(m control.Mode) {
+	c.mu.Lock()
+	c.mode = m
+	c.mu.Unlock()
+
+	c.Telemetry.Update(func(d *telemetry.Data) { d.Mode = string(m) })
+	c.Log.Info("mode", runlog.Fields{"mode": m})
+}
+
+// NextMode switches to the next drive mode.
+func (c *Car) NextMode() {
+	c.SetMode(c.Mode().Next())
+}
+
+// SetSteering sets the steering from the driver, from -1 (left) to 1 (right).
+func (c *Car) SetSteering(steering float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.user.steering = clamp(steering, -1, 1)
+}
+
+// SetThrottle sets the throttle from the driver, from -1 (reverse) to 1
+// (forward). It is scaled by the MaxThrottle of the car.
+func (c *Car) SetThrottle(throttle float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.user.throttle = clamp(throttle, -1, 1) * c.Config.Car.MaxThrottle
+}
+
+// Stopped returns true while a crash or a failed device is stopping the car.
+func (c *Car) Stopped() bool {
+	_, crashed := c.Crash.Crashed()
+	return crashed || len(c.Devices.Failed()) > 0
+}
+
+// Reset clears a crash or failed devices so the car can drive again.
+func (c *Car) Reset() {
+	c.Crash.Reset()
+	c.Devices.Reset()
+	c.Stabilizer.Reset()
+	c.Telemetry.Update(func(d *telemetry.Data) {
+		d.Crash = nil
+		d.FailedDevices = nil
+	})
+}
+
+// step is one run of the drive loop.
+func (c *Car) step() {
+	c.updateIMU()
+	c.updateOdometer()
+
+	start := time.Now()
+	c.mu.Lock()
+	mode := c.mode
+	steering, throttle := c.controls()
+	captured := c.steeringFrame
+	c.mu.Unlock()
+
+	// while cruising the throttle is the target speed, until the loop below
+	cruising := mode == control.Local && c.Cruise != nil && c.Odometer != nil
+	if cruising && throttle > 0 {
+		throttle = c.Cruise.Config.Speed
+	}
+	if mode != control.User && c.Behaviour != nil {
+		throttle = c.Behaviour.Throttle(throttle)
+	}
+	obstacle := false
+	if c.Range != nil {
+		limited := c.Collision.Limit(throttle, start)
+		obstacle = limited < throttle
+		throttle = limited
+	}
+	if c.Stopped() {
+		throttle = 0
+	}
+	target := 0.0
+	if cruising {
+		target = throttle
+		throttle = c.Cruise.Update(target, c.Odometer.Speed(), start)
+	} else if c.Cruise != nil {
+		c.Cruise.Reset()
+	}
+	// the stabilizer only makes sense driving forward; stopped, the yaw
+	// rate stays 0 whatever the steering and the integral would wind up
+	if c.Orientation != nil && c.Config.Stabilizer.Enabled(mode) && throttle > 0 {
+		steering = c.Stabilizer.Update(steering, c.Orientation.Attitude().YawRate)
+	} else {
+		c.Stabilizer.Reset()
+	}
+	c.Timings.Timer(metrics.StageControl).Since(start)
+
+	start = time.Now()
+	if err := c.Actuator.SetSteering(steering); err != nil {
+		c.Log.Limit("steering", time.Second, runlog.Error, "setting steering failed", runlog.Fields{"error": err.Error()})
+	}
+	if err := c.Actuator.SetThrottle(throttle); err != nil {
+		c.Log.Limit("throttle", time.Second, runlog.Error, "setting throttle failed", runlog.Fields{"error": err.Error()})
+	}
+	c.Timings.Timer(metrics.StageActuation).Since(start)
+
+	c.Telemetry.Update(func(d *telemetry.Data) {
+		d.Steering = steering
+		d.Throttle = throttle
+		d.Obstacle = obstacle
+		d.TargetSpeed = target
+	})
+
+	// the first time the steering from a new frame is sent, record how long
+	// it took from reading the frame
+	if mode != control.User && captured.After(c.actuatedFrame) {
+		latency := time.Since(captured)
+		c.Timings.Timer(metrics.StageLatency).Observe(latency)
+		c.Telemetry.Update(func(d *telemetry.Data) { d.LatencyMS = latency.Seconds() * 1000 })
+		c.actuatedFrame = captured
+	}
+}
+
+// controls returns the steering and throttle for the drive mode. It must be
+// called with c.mu held.
+func (c *Car) controls() (steering, throttle float64) {
+	switch c.mode {
+	case control.LocalAngle:
+		return c.pilot.steering, c.user.throttle
+	case control.Local:
+		return c.pilot.steering, c.pilot.throttle
+	}
+	return c.user.steering, c.user.throttle
+}
+
+func (c *Car) updateIMU() {
+	if c.Orientation == nil {
+		return
+	}
+	if err := c.Orientation.Update(); err != nil {
+		c.Log.Limit("imu", time.Second, runlog.Error, "reading IMU failed", runlog.Fields{"error": err.Error()})
+		return
+	}
+
+	reading, attitude := c.Orientation.Reading(), c.Orientation.Attitude()
+	c.Telemetry.Update(func(d *telemetry.Data) {
+		d.IMU = reading
+		d.Attitude = attitude
+	})
+
+	if event := c.Crash.Check(reading, attitude); event != nil {
+		c.crashed(*event)
+	}
+}
+
+func (c *Car) updateOdometer() {
+	if c.Odometer == nil {
+		return
+	}
+	speed, distance := c.Odometer.Update(time.Now())
+	c.Telemetry.Update(func(d *telemetry.Data) {
+		d.Speed = speed
+		d.Odometer = distance
+	})
+}
+
+// updateRange reads the distance to the nearest obstacle for the Collision
+// brake.
+func (c *Car) updateRange() {
+	d, err := c.Range.Distance()
+	if err != nil {
+		c.Log.Limit("range", time.Second, runlog.Error, "reading range sensor failed", runlog.Fields{"error": err.Error()})
+		return
+	}
+	c.Collision.Update(d, time.Now())
+	c.Telemetry.Update(func(t *telemetry.Data) { t.Range = d })
+}
+
+// crashed cuts the throttle straight away, then logs the crash along with a
+// snapshot of the last camera frame.
+func (c *Car) crashed(event imu.Event) {
+	c.Actuator.SetThrottle(0)
+	c.Telemetry.Update(func(d *telemetry.Data) {
+		d.Throttle = 0
+		d.Crash = &event
+		d.WatchdogTrips++
+	})
+
+	fields := runlog.Fields{"event": event}
+	if buf, ok := c.lastJPEG.Load().([]byte); ok {
+		name := filepath.Join(c.SnapshotDir, "crash-"+event.Time.Format("20060102-150405")+".jpg")
+		if err := ioutil.WriteFile(name, buf, 0644); err != nil {
+			fields["snapshot_error"] = err.Error()
+		} else {
+			fields["snapshot"] = name
+		}
+	}
+	c.Log.Warn("crash detected, driving is stopped until the car is reset", fields)
+}
+
+// deviceFailed cuts the throttle when an I2C device keeps failing.
+func (c *Car) deviceFailed(h device.Health) {
+	c.Actuator.SetThrottle(0)
+	c.Telemetry.Update(func(d *telemetry.Data) {
+		d.Throttle = 0
+		d.FailedDevices = c.Devices.Failed()
+		d.WatchdogTrips++
+	})
+	c.Log.Error("device failed, driving is stopped until the car is reset", runlog.Fields{"device": h})
+}
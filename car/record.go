@@ -0,0 +1,129 @@
+package car
+
+import (
+	"time"
+
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/runlog"
+	"github.com/hybridgroup/gophercar/telemetry"
+	"github.com/hybridgroup/gophercar/tub"
+)
+
+// recordQueue is the number of records waiting to be written before new ones
+// are dropped.
+const recordQueue = 32
+
+// Record starts recording camera frames along with the steering, throttle and
+// mode, and the speed if the car has an Odometer, to t. Like the Donkeycar,
+// frames are only recorded while the throttle is not zero.
+func (c *Car) Record(t *tub.Tub) {
+	c.StopRecording()
+
+	if c.Odometer != nil {
+		if err := t.AddInputs(tub.OdometryMeta); err != nil {
+			c.Log.Error("adding the speed to the tub failed", runlog.Fields{"error": err.Error()})
+		}
+	}
+
+	records := make(chan record, recordQueue)
+	c.mu.Lock()
+	c.tub = t
+	c.records = records
+	c.recording = true
+	c.mu.Unlock()
+
+	c.Telemetry.Update(func(d *telemetry.Data) { d.Recording = true })
+	c.Log.Info("recording", runlog.Fields{"tub": t.Path})
+
+	go func() {
+		for r := range records {
+			if _, err := t.Write(r.r, r.jpeg); err != nil {
+				c.Log.Limit("record", time.Second, runlog.Error, "writing record failed", runlog.Fields{"error": err.Error()})
+				continue
+			}
+			c.Telemetry.Update(func(d *telemetry.Data) { d.Records++ })
+		}
+	}()
+}
+
+// StopRecording stops recording, if the car is recording.
+func (c *Car) StopRecording() {
+	c.mu.Lock()
+	records := c.records
+	if records != nil {
+		close(records)
+	}
+	c.records = nil
+	c.recording = false
+	c.mu.Unlock()
+
+	if records == nil {
+		return
+	}
+	c.Telemetry.Update(func(d *telemetry.Data) { d.Recording = false })
+	c.Log.Info("recording stopped", nil)
+}
+
+// ToggleRecording pauses or resumes recording to the current tub.
+func (c *Car) ToggleRecording() {
+	c.mu.Lock()
+	t, recording := c.tub, c.recording
+	c.mu.Unlock()
+
+	switch {
+	case recording:
+		c.StopRecording()
+	case t != nil:
+		c.Record(t)
+	}
+}
+
+// Recording returns true while the car is recording.
+func (c *Car) Recording() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recording
+}
+
+// recordFrame queues img to be written to the tub with the current controls.
+func (c *Car) recordFrame(img gocv.Mat, captured time.Time) {
+	c.mu.Lock()
+	recording := c.recording
+	mode := c.mode
+	steering, throttle := c.controls()
+	c.mu.Unlock()
+
+	if !recording || throttle == 0 {
+		return
+	}
+
+	gocv.Resize(img, &c.recordImg, RecordSize, 0, 0, gocv.InterpolationArea)
+	buf, err := gocv.IMEncode(".jpg", c.recordImg)
+	if err != nil {
+		c.Log.Limit("record", time.Second, runlog.Error, "encoding record failed", runlog.Fields{"error": err.Error()})
+		return
+	}
+
+	r := tub.Record{
+		Angle:        steering,
+		Throttle:     throttle,
+		Mode:         string(mode),
+		Milliseconds: captured.UnixNano() / int64(time.Millisecond),
+	}
+	if c.Odometer != nil {
+		r.Speed, r.Distance = c.Odometer.Speed(), c.Odometer.Distance()
+	}
+
+	// the recording may have been stopped while encoding
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.records == nil {
+		return
+	}
+	select {
+	case c.records <- record{r: r, jpeg: buf}:
+	default:
+		c.Log.Limit("record queue", time.Second, runlog.Warn, "record dropped, tub writer is behind", nil)
+	}
+}
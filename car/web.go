@@ -0,0 +1,84 @@
+package car
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/hybridgroup/gophercar/control"
+	"github.com/hybridgroup/gophercar/metrics"
+	"github.com/hybridgroup/gophercar/runlog"
+)
+
+// Handler returns the web server of the car:
+//
+//	/                 the video stream, with the HUD
+//	/raw              the camera video without annotations, if Raw is set
+//	/stage/<name>     each stage of the vision processing, if Debug is set
+//	/api/telemetry    the latest state of the car as JSON
+//	/api/timing       how long each stage of the car takes
+//	/api/devices      the health of each I2C device
+//	/api/mode         the drive mode, changed with a POST of {"mode": "local"}
+//	/api/reset        POST to drive again after a crash or a failed device
+//	/metrics          Prometheus metrics, if Metrics is set
+func (c *Car) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", c.stream)
+	if c.Raw {
+		mux.Handle("/raw", c.rawStream)
+	}
+	if c.Debug {
+		c.Stage()
+		c.stages.Handle(mux, "/stage")
+	}
+	mux.Handle("/api/telemetry", c.Telemetry)
+	mux.Handle("/api/timing", c.Timings)
+	mux.Handle("/api/devices", c.Devices)
+	mux.HandleFunc("/api/mode", c.handleMode)
+	mux.HandleFunc("/api/reset", c.handleReset)
+	if c.Metrics {
+		mux.Handle("/metrics", metrics.Handler(c.Telemetry, c.Timings, c.Devices, metrics.Loops{c.DriveLoop}))
+	}
+	return mux
+}
+
+// handleReset lets the operator clear a crash or a failed device so the car
+// can drive again.
+func (c *Car) handleReset(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c.Reset()
+	c.Log.Info("reset by operator", runlog.Fields{"remote": req.RemoteAddr})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type modeRequest struct {
+	Mode control.Mode `json:"mode"`
+}
+
+// handleMode returns the drive mode, or changes it on a POST.
+func (c *Car) handleMode(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var body modeRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		m, err := control.ParseMode(string(body.Mode))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.SetMode(m)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(modeRequest{Mode: c.Mode()})
+}
@@ -2,6 +2,7 @@
 package main
 
 import (
+	"log"
 	"math"
 	"time"
 
@@ -9,6 +10,9 @@ import (
 	"gobot.io/x/gobot"
 	"gobot.io/x/gobot/drivers/i2c"
 	"gobot.io/x/gobot/platforms/raspi"
+
+	"github.com/hybridgroup/gophercar/config"
+	"github.com/hybridgroup/gophercar/imu"
 )
 
 var (
@@ -17,6 +21,9 @@ var (
 	oled    *i2c.SSD1306Driver
 	mpu6050 *i2c.MPU6050Driver
 
+	orientation *imu.IMU
+	imuInterval = 10 * time.Millisecond
+
 	ctx *gg.Context
 )
 
@@ -30,10 +37,16 @@ var (
 )
 
 func main() {
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	r = raspi.NewAdaptor()
 	pca9685 = i2c.NewPCA9685Driver(r)
 	oled = i2c.NewSSD1306Driver(r)
 	mpu6050 = i2c.NewMPU6050Driver(r)
+	orientation = imu.New(imu.NewMPU6050(mpu6050, cfg.IMU), imuInterval)
 
 	ctx = gg.NewContext(oled.Buffer.Width, oled.Buffer.Height)
 
@@ -42,8 +55,10 @@ func main() {
 			handleOLED()
 		})
 
-		gobot.Every(100*time.Millisecond, func() {
-			handleAccel()
+		// measure the gyro bias while we are still standing still
+		orientation.CalibrateGyro(100)
+		gobot.Every(imuInterval, func() {
+			handleIMU()
 		})
 
 		// init the PWM controller
@@ -75,12 +90,8 @@ func handleOLED() {
 	oled.ShowImage(ctx.Image())
 }
 
-func handleAccel() {
-	mpu6050.GetData()
-
-	// fmt.Println("Accelerometer", mpu6050.Accelerometer)
-	// fmt.Println("Gyroscope", mpu6050.Gyroscope)
-	// fmt.Println("Temperature", mpu6050.Temperature)
+func handleIMU() {
+	orientation.Update()
 }
 
 func handleSteering() {
@@ -1,23 +1,57 @@
-// this does not really do anything yet except connect to all of the various devices
+// Shows each page of the OLED dashboard in turn, using some sample telemetry.
+//
+// How to run:
+//
+// oledtest [png]
+//
+// With the "png" argument no hardware is needed. Each page is rendered into
+// page-N.png in the current directory instead, so the layout can be checked
+// on a PC.
 package main
 
 import (
+	"fmt"
+	"log"
+	"os"
 	"time"
 
 	"github.com/fogleman/gg"
 	"gobot.io/x/gobot"
 	"gobot.io/x/gobot/drivers/i2c"
 	"gobot.io/x/gobot/platforms/raspi"
+
+	"github.com/hybridgroup/gophercar/dashboard"
+	"github.com/hybridgroup/gophercar/imu"
+	"github.com/hybridgroup/gophercar/telemetry"
 )
 
 var (
 	r    *raspi.Adaptor
 	oled *i2c.SSD1306Driver
 
-	ctx *gg.Context
+	ctx  *gg.Context
+	dash *dashboard.Dashboard
+
+	sample = telemetry.Data{
+		Mode:      "local",
+		Steering:  -0.4,
+		Throttle:  0.3,
+		Attitude:  imu.Attitude{Roll: 1.2, Pitch: -3.4, Yaw: 120, YawRate: -45},
+		LineFound: true,
+		Recording: true,
+		Records:   1234,
+		Address:   "0.0.0.0:8080",
+	}
 )
 
 func main() {
+	dash = dashboard.New()
+
+	if len(os.Args) > 1 && os.Args[1] == "png" {
+		savePages()
+		return
+	}
+
 	r = raspi.NewAdaptor()
 	oled = i2c.NewSSD1306Driver(r)
 
@@ -27,6 +61,10 @@ func main() {
 		gobot.Every(1*time.Second, func() {
 			handleOLED()
 		})
+
+		gobot.Every(3*time.Second, func() {
+			dash.Next()
+		})
 	}
 
 	robot := gobot.NewRobot("gophercar",
@@ -39,9 +77,23 @@ func main() {
 }
 
 func handleOLED() {
-	ctx.SetRGB(0, 0, 0)
-	ctx.Clear()
-	ctx.SetRGB(1, 1, 1)
-	ctx.DrawStringAnchored(time.Now().Format("15:04:05"), 0, 0, 0, 1)
+	if temp, err := telemetry.CPUTemperature(); err == nil {
+		sample.CPUTemperature = temp
+	}
+
+	dash.Render(ctx, sample)
 	oled.ShowImage(ctx.Image())
 }
+
+func savePages() {
+	ctx = gg.NewContext(dashboard.Width, dashboard.Height)
+	for i := range dash.Pages {
+		dash.RenderPage(ctx, i, sample)
+
+		name := fmt.Sprintf("page-%d.png", i+1)
+		if err := ctx.SavePNG(name); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("Saved", name)
+	}
+}
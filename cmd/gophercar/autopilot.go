@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/hybridgroup/gophercar/car"
+	"github.com/hybridgroup/gophercar/control"
+)
+
+var (
+	autopilotOptions carOptions
+	autopilotMode    string
+)
+
+var autopilotCmd = &command{
+	Name:  "autopilot",
+	Short: "let the pilot drive",
+	SetFlags: func(fs *flag.FlagSet) {
+		autopilotOptions.setFlags(fs, "none")
+		fs.StringVar(&autopilotMode, "mode", string(control.Local), "drive mode: local to let the pilot steer and drive, or local_angle to only steer")
+		fs.Float64Var(&cfg.Car.Throttle, "throttle", cfg.Car.Throttle, "throttle of the pilot")
+		fs.Float64Var(&cfg.Cruise.Speed, "speed", cfg.Cruise.Speed, "speed in m/s to drive at with the wheel encoder, instead of a fixed throttle")
+	},
+	Run: runAutopilot,
+}
+
+func runAutopilot(args []string) error {
+	if len(args) > 0 {
+		return usageError("autopilot takes no arguments")
+	}
+
+	return runCar("autopilot", &autopilotOptions, func(c *car.Car) error {
+		if err := setPilot(c); err != nil {
+			return err
+		}
+		return setMode(c, autopilotMode)
+	})
+}
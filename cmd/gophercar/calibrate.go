@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hybridgroup/gophercar/imu"
+)
+
+// imuCalibrationSamples is the number of samples averaged to calibrate the IMU.
+var imuCalibrationSamples = 500
+
+// encoderCalibrationDistance is how far in metres the car is pushed to
+// calibrate the encoder.
+var encoderCalibrationDistance = 2.0
+
+var calibrateCmd = &command{
+	Name:  "calibrate",
+	Args:  "steering|throttle|imu|encoder",
+	Short: "find the PWM pulses of the servo and ESC, or calibrate the IMU or encoder",
+	SetFlags: func(fs *flag.FlagSet) {
+		fs.IntVar(&imuCalibrationSamples, "samples", imuCalibrationSamples, "number of IMU samples to average")
+		fs.Float64Var(&encoderCalibrationDistance, "distance", encoderCalibrationDistance, "metres to push the car to calibrate the encoder")
+	},
+	Run: runCalibrate,
+}
+
+func runCalibrate(args []string) error {
+	if len(args) != 1 {
+		return usageError("calibrate needs steering, throttle, imu or encoder")
+	}
+	if imuCalibrationSamples < 1 {
+		return usageError("samples must be at least 1")
+	}
+
+	hw, err := newHardware(false)
+	if err != nil {
+		return err
+	}
+	if err := hw.start(); err != nil {
+		return err
+	}
+	defer hw.halt()
+
+	switch args[0] {
+	case "steering":
+		return calibratePWM(hw, cfg.Car.SteeringChannel, map[string]*int{
+			"left":  &cfg.Car.SteeringLeftPulse,
+			"right": &cfg.Car.SteeringRightPulse,
+		})
+	case "throttle":
+		// the ESC needs to see the stopped pulse before it will drive
+		if err := hw.pwm.SetPWM(cfg.Car.ThrottleChannel, 0, uint16(cfg.Car.ThrottleStoppedPulse)); err != nil {
+			return err
+		}
+		return calibratePWM(hw, cfg.Car.ThrottleChannel, map[string]*int{
+			"forward": &cfg.Car.ThrottleForwardPulse,
+			"stopped": &cfg.Car.ThrottleStoppedPulse,
+			"reverse": &cfg.Car.ThrottleReversePulse,
+		})
+	case "imu":
+		return calibrateIMU(hw)
+	case "encoder":
+		return calibrateEncoder(hw)
+	}
+	return usageError(fmt.Sprintf("cannot calibrate %q", args[0]))
+}
+
+// calibratePWM sends each pulse typed in to the channel, so the ends of the
+// steering and the throttle can be found. Typing one of the names of pulses
+// saves the last pulse as that setting.
+func calibratePWM(hw *hardware, channel int, pulses map[string]*int) error {
+	if err := hw.pwm.SetPWMFreq(cfg.Car.PWMFrequency); err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range pulses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("%s pulse is %d\n", name, *pulses[name])
+	}
+	fmt.Printf("Enter a pulse to send to channel %d, one of %s to save the last pulse, or q to quit.\n",
+		channel, strings.Join(names, ", "))
+
+	last := -1
+	changed := false
+	in := bufio.NewScanner(os.Stdin)
+	for fmt.Print("> "); in.Scan(); fmt.Print("> ") {
+		text := strings.TrimSpace(in.Text())
+		if text == "q" {
+			break
+		}
+
+		if p, ok := pulses[text]; ok {
+			if last < 0 {
+				fmt.Println("Enter a pulse first")
+				continue
+			}
+			*p = last
+			changed = true
+			fmt.Printf("%s pulse set to %d\n", text, last)
+			continue
+		}
+
+		pulse, err := strconv.Atoi(text)
+		if err != nil || pulse < 0 || pulse > 4095 {
+			fmt.Println("Pulses are from 0 to 4095")
+			continue
+		}
+		if err := hw.pwm.SetPWM(channel, 0, uint16(pulse)); err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		last = pulse
+	}
+
+	if !changed {
+		return in.Err()
+	}
+	if err := cfg.Save(*configPath); err != nil {
+		return err
+	}
+	fmt.Println("Saved to", *configPath)
+	return nil
+}
+
+// calibrateIMU measures the offsets from the raw sensor data and saves them
+// to the config file.
+func calibrateIMU(hw *hardware) error {
+	fmt.Println("Calibrating, keep the car still and level...")
+
+	cal, err := imu.Calibrate(imu.NewMPU6050(hw.mpu6050, imu.Calibration{}), imuCalibrationSamples, time.Duration(cfg.Car.LoopInterval))
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Samples", cal.Samples)
+	fmt.Println("Accelerometer offset", cal.AccelOffset, "noise", cal.AccelNoise)
+	fmt.Println("Gyroscope offset", cal.GyroOffset, "noise", cal.GyroNoise)
+
+	cfg.IMU = cal
+	if err := cfg.Save(*configPath); err != nil {
+		return err
+	}
+	fmt.Println("Saved to", *configPath)
+	return nil
+}
+
+// calibrateEncoder counts the pulses while the car is pushed a measured
+// distance, and saves the pulses per metre to the config file.
+func calibrateEncoder(hw *hardware) error {
+	if hw.encoder == nil {
+		return errors.New("no encoder_pin in the config")
+	}
+
+	fmt.Printf("Push the car %.2fm in a straight line, then press enter.\n", encoderCalibrationDistance)
+	start := hw.encoder.Count()
+	if _, err := bufio.NewReader(os.Stdin).ReadString('\n'); err != nil {
+		return err
+	}
+	pulses := hw.encoder.Count() - start
+	if pulses == 0 {
+		return fmt.Errorf("no pulses counted on pin %s", cfg.Car.EncoderPin)
+	}
+
+	cfg.Car.EncoderPulsesPerMetre = float64(pulses) / encoderCalibrationDistance
+	fmt.Printf("%d pulses, %.1f pulses per metre\n", pulses, cfg.Car.EncoderPulsesPerMetre)
+	if err := cfg.Save(*configPath); err != nil {
+		return err
+	}
+	fmt.Println("Saved to", *configPath)
+	return nil
+}
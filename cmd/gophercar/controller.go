@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gobot.io/x/gobot"
+	"gobot.io/x/gobot/platforms/joystick"
+	"gobot.io/x/gobot/platforms/keyboard"
+
+	"github.com/hybridgroup/gophercar/car"
+)
+
+// controller is how the driver drives the car.
+type controller interface {
+	connections() []gobot.Connection
+	devices() []gobot.Device
+
+	// start handles the input of the driver. nextPage shows the next page of
+	// the dashboard.
+	start(c *car.Car, nextPage func())
+}
+
+// newController returns the controller with the name kind.
+func newController(kind string) (controller, error) {
+	switch kind {
+	case "joystick":
+		return newJoystickController(), nil
+	case "keyboard":
+		return newKeyboardController(), nil
+	case "none", "":
+		return noController{}, nil
+	}
+	return nil, fmt.Errorf("unknown controller %q, use joystick, keyboard or none", kind)
+}
+
+// noController leaves the car to the pilot and the web API.
+type noController struct{}
+
+func (noController) connections() []gobot.Connection   { return nil }
+func (noController) devices() []gobot.Device           { return nil }
+func (noController) start(c *car.Car, nextPage func()) {}
+
+// joystickController drives with a ds3 controller:
+//
+//	left stick - throttle
+//	right stick - steering
+//	triangle - next dashboard page
+//	select - next drive mode
+//	circle - pause or resume recording
+//	start - drive again after a crash or a failed device
+type joystickController struct {
+	adaptor *joystick.Adaptor
+	stick   *joystick.Driver
+}
+
+// joystickDeadZone is how far a stick can move from the centre before it
+// counts.
+const joystickDeadZone = 10
+
+func newJoystickController() *joystickController {
+	a := joystick.NewAdaptor()
+	return &joystickController{adaptor: a, stick: joystick.NewDriver(a, "dualshock3")}
+}
+
+func (j *joystickController) connections() []gobot.Connection {
+	return []gobot.Connection{j.adaptor}
+}
+
+func (j *joystickController) devices() []gobot.Device {
+	return []gobot.Device{j.stick}
+}
+
+func (j *joystickController) start(c *car.Car, nextPage func()) {
+	j.stick.On(joystick.RightX, func(data interface{}) {
+		c.SetSteering(stickValue(data.(int16)))
+	})
+	j.stick.On(joystick.LeftY, func(data interface{}) {
+		c.SetThrottle(stickValue(data.(int16)))
+	})
+	j.stick.On(joystick.TrianglePress, func(data interface{}) {
+		nextPage()
+	})
+	j.stick.On(joystick.SelectPress, func(data interface{}) {
+		c.NextMode()
+	})
+	j.stick.On(joystick.CirclePress, func(data interface{}) {
+		c.ToggleRecording()
+	})
+	j.stick.On(joystick.StartPress, func(data interface{}) {
+		c.Reset()
+	})
+}
+
+// stickValue rescales a stick axis to -1 to 1, with a dead zone in the centre.
+func stickValue(v int16) float64 {
+	if v > -joystickDeadZone && v < joystickDeadZone {
+		return 0
+	}
+	return gobot.Rescale(float64(v), -32767.0, 32767.0, -1.0, 1.0)
+}
+
+// keyboardController drives with the keyboard of the terminal:
+//
+//	up arrow - forward for a second
+//	down arrow - backward for a second
+//	right arrow - turn right
+//	left arrow - turn left
+//	spacebar - next dashboard page
+//	m - next drive mode
+//	t - pause or resume recording
+//	r - drive again after a crash or a failed device
+type keyboardController struct {
+	keys     *keyboard.Driver
+	steering float64
+}
+
+// keyboardSteeringStep is how far each arrow key press turns the steering.
+const keyboardSteeringStep = 0.1
+
+func newKeyboardController() *keyboardController {
+	return &keyboardController{keys: keyboard.NewDriver()}
+}
+
+func (k *keyboardController) connections() []gobot.Connection { return nil }
+
+func (k *keyboardController) devices() []gobot.Device {
+	return []gobot.Device{k.keys}
+}
+
+func (k *keyboardController) start(c *car.Car, nextPage func()) {
+	k.keys.On(keyboard.Key, func(data interface{}) {
+		key := data.(keyboard.KeyEvent)
+
+		switch key.Key {
+		case keyboard.ArrowUp:
+			c.SetThrottle(1)
+			gobot.After(1*time.Second, func() { c.SetThrottle(0) })
+		case keyboard.ArrowDown:
+			c.SetThrottle(-1)
+			gobot.After(1*time.Second, func() { c.SetThrottle(0) })
+		case keyboard.ArrowRight:
+			if k.steering < 1.0 {
+				k.steering = round(k.steering+keyboardSteeringStep, 0.05)
+			}
+			c.SetSteering(k.steering)
+		case keyboard.ArrowLeft:
+			if k.steering > -1.0 {
+				k.steering = round(k.steering-keyboardSteeringStep, 0.05)
+			}
+			c.SetSteering(k.steering)
+		case keyboard.Spacebar:
+			nextPage()
+		case keyboard.M:
+			c.NextMode()
+		case keyboard.T:
+			c.ToggleRecording()
+		case keyboard.R:
+			c.Reset()
+		}
+	})
+}
+
+func round(x, unit float64) float64 {
+	return math.Round(x/unit) * unit
+}
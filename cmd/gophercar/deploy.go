@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/hybridgroup/gophercar/deploy"
+	"github.com/hybridgroup/gophercar/deploy/sshtest"
+)
+
+var (
+	deployBinary       string
+	deployInsecure     bool
+	deployStandIn      string
+	deployLogLines     int
+	deployBuildPackage string
+)
+
+var deployCmd = &command{
+	Name:  "deploy",
+	Args:  "[install|start|stop|restart|status|logs]",
+	Short: "build the car, copy it to the Pi and run it as a systemd service",
+	SetFlags: func(fs *flag.FlagSet) {
+		d := &cfg.Deploy
+		fs.StringVar(&d.Host, "host", d.Host, "host or host:port of the Pi")
+		fs.StringVar(&d.User, "user", d.User, "user to log in to the Pi as")
+		fs.StringVar(&d.Dir, "dir", d.Dir, "directory on the Pi to install to")
+		fs.StringVar(&d.Service, "service", d.Service, "name of the systemd service")
+		fs.StringVar(&d.Run, "run", d.Run, "gophercar command and flags for the service to run")
+		fs.StringVar(&d.GOARM, "goarm", d.GOARM, "ARM version to build for")
+		fs.StringVar(&d.CC, "cc", d.CC, "C cross compiler for linux/arm")
+		fs.StringVar(&d.CXX, "cxx", d.CXX, "C++ cross compiler for linux/arm")
+		fs.StringVar(&d.Sysroot, "sysroot", d.Sysroot, "sysroot with the Raspbian OpenCV headers and libraries")
+		fs.StringVar(&deployBinary, "binary", "", "copy this binary instead of building one")
+		fs.StringVar(&deployBuildPackage, "package", deploy.DefaultPackage, "package to build")
+		fs.BoolVar(&deployInsecure, "insecure", false, "do not check the host key of the Pi against ~/.ssh/known_hosts")
+		fs.StringVar(&deployStandIn, "standin", "", "deploy to a local SSH server standing in for the Pi, installing to this directory")
+		fs.IntVar(&deployLogLines, "n", 50, "number of lines to show for logs")
+	},
+	Run: runDeploy,
+}
+
+func runDeploy(args []string) error {
+	action := "install"
+	if len(args) > 1 {
+		return usageError("deploy takes at most one action")
+	}
+	if len(args) == 1 {
+		action = args[0]
+	}
+
+	remote, closeRemote, err := dialDeploy()
+	if err != nil {
+		return err
+	}
+	defer closeRemote()
+
+	unit := deploy.Unit{
+		Name: cfg.Deploy.Service,
+		User: cfg.Deploy.User,
+		Dir:  cfg.Deploy.Dir,
+		Run:  cfg.Deploy.Run,
+	}
+
+	var out []byte
+	switch action {
+	case "install":
+		err = install(remote, unit)
+	case "logs":
+		out, err = unit.Logs(remote, deployLogLines)
+	default:
+		out, err = unit.Control(remote, action)
+	}
+	os.Stdout.Write(out)
+	return err
+}
+
+// dialDeploy connects to the Pi, or to a stand-in for it.
+func dialDeploy() (deploy.Remote, func(), error) {
+	if deployStandIn == "" {
+		if cfg.Deploy.Host == "" {
+			return nil, nil, usageError("set the host of the Pi with -host, or deploy.host in the config")
+		}
+		hostKey := ssh.InsecureIgnoreHostKey()
+		if !deployInsecure {
+			var err error
+			if hostKey, err = deploy.KnownHosts(); err != nil {
+				return nil, nil, fmt.Errorf("%v, use -insecure to skip checking the host key", err)
+			}
+		}
+		remote, err := deploy.Dial(cfg.Deploy.Host, cfg.Deploy.User, deploy.Auth(), hostKey)
+		if err != nil {
+			return nil, nil, err
+		}
+		return remote, func() { remote.Close() }, nil
+	}
+
+	server, err := sshtest.NewServer(deployStandIn)
+	if err != nil {
+		return nil, nil, err
+	}
+	dir := server.Root
+	cfg.Deploy.Host = server.Addr
+	cfg.Deploy.Dir = filepath.ToSlash(dir)
+	if u, err := user.Current(); err == nil {
+		cfg.Deploy.User = u.Username
+	}
+	fmt.Printf("Deploying to a stand-in at %s, installing to %s\n", server.Addr, dir)
+
+	remote, err := deploy.Dial(server.Addr, cfg.Deploy.User, []ssh.AuthMethod{server.ClientAuth()}, ssh.FixedHostKey(server.HostKey()))
+	if err != nil {
+		server.Close()
+		return nil, nil, err
+	}
+	return remote, func() {
+		remote.Close()
+		server.Close()
+		fmt.Println("Commands run on the stand-in:")
+		for _, cmd := range server.Commands() {
+			fmt.Println("  " + cmd)
+		}
+	}, nil
+}
+
+// install builds the car, copies it with its config, and installs and
+// restarts the service.
+func install(remote deploy.Remote, unit deploy.Unit) error {
+	binary := deployBinary
+	if binary == "" {
+		binary = filepath.Join("build", "gophercar-linux-arm")
+		fmt.Println("Building", binary)
+		b := deploy.Build{
+			Package: deployBuildPackage,
+			Output:  binary,
+			GOARM:   cfg.Deploy.GOARM,
+			CC:      cfg.Deploy.CC,
+			CXX:     cfg.Deploy.CXX,
+			Sysroot: cfg.Deploy.Sysroot,
+		}
+		if err := b.Run(os.Stderr); err != nil {
+			return fmt.Errorf("building: %v", err)
+		}
+	}
+
+	// the config is written as it is now, with any flags applied
+	config, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	unitFile, err := unit.File()
+	if err != nil {
+		return err
+	}
+	files := []deploy.File{
+		{Name: "gophercar", Local: binary, Mode: 0755},
+		{Name: "gophercar.json", Data: config, Mode: 0644},
+		unitFile,
+	}
+	for _, f := range cfg.Deploy.Files {
+		files = append(files, deploy.File{Name: filepath.Base(f), Local: f, Mode: 0644})
+	}
+
+	fmt.Printf("Copying to %s:%s\n", cfg.Deploy.Host, unit.Dir)
+	copied, err := deploy.Sync(remote, unit.Dir, files, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if copied == 0 {
+		fmt.Println("Nothing has changed")
+	}
+
+	fmt.Println("Installing the", unit.Name, "service")
+	if err := unit.Install(remote); err != nil {
+		return err
+	}
+	if _, err := unit.Control(remote, "restart"); err != nil {
+		return err
+	}
+	fmt.Printf("Restarted, running: gophercar %s\n", unit.Run)
+	return nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	"github.com/hybridgroup/gophercar/car"
+	"github.com/hybridgroup/gophercar/control"
+	"github.com/hybridgroup/gophercar/detect"
+	"github.com/hybridgroup/gophercar/pilot"
+)
+
+var driveOptions carOptions
+
+var driveCmd = &command{
+	Name:  "drive",
+	Short: "drive with a joystick or the keyboard",
+	SetFlags: func(fs *flag.FlagSet) {
+		driveOptions.setFlags(fs, "joystick")
+	},
+	Run: runDrive,
+}
+
+func runDrive(args []string) error {
+	if len(args) > 0 {
+		return usageError("drive takes no arguments")
+	}
+	return runCar("drive", &driveOptions, setPilot)
+}
+
+// setPilot gives the car the pilot, so that the driver can switch to one of
+// the local modes.
+func setPilot(c *car.Car) error {
+	p, err := newPilot()
+	if err != nil {
+		return err
+	}
+	if line, ok := p.(*pilot.Line); ok {
+		line.Stage = c.Stage()
+	}
+	c.Pilot = p
+	return nil
+}
+
+// newPilot returns a pilot that drives with the model in the config if there
+// is one, or else follows the line.
+func newPilot() (pilot.Pilot, error) {
+	if cfg.Car.Model != "" {
+		return pilot.LoadCNN(cfg.Car.Model)
+	}
+	return pilot.NewLine(cfg.Car.Throttle), nil
+}
+
+// setCruise turns on the cruise control if the config has a target speed.
+func setCruise(c *car.Car) error {
+	if cfg.Cruise.Speed <= 0 {
+		return nil
+	}
+	if c.Odometer == nil {
+		return errors.New("cruise control needs a wheel encoder, set encoder_pin in the config")
+	}
+	c.Cruise = control.NewCruise(cfg.Cruise)
+	return nil
+}
+
+// setDetector gives the car the object detector in the config, if there is
+// one, to stop, wait or slow down for what it sees.
+func setDetector(c *car.Car) error {
+	d := cfg.Detect
+	if !d.Enabled() {
+		return nil
+	}
+	if d.Cascade != "" {
+		cascade, err := detect.NewCascade(d.Cascade, d.CascadeClass)
+		if err != nil {
+			return err
+		}
+		c.Detector = cascade
+	} else {
+		ssd, err := detect.NewSSD(d.Model, d.ModelConfig)
+		if err != nil {
+			return err
+		}
+		ssd.MinConfidence = d.Confidence
+		if d.Labels != "" {
+			if ssd.Labels, err = detect.LoadLabels(d.Labels); err != nil {
+				ssd.Close()
+				return err
+			}
+		}
+		c.Detector = ssd
+	}
+	if d.Every > 0 {
+		c.DetectEvery = d.Every
+	}
+	c.Behaviour = control.NewBehaviour(d.Rules)
+	return nil
+}
+
+// setMode sets the drive mode from its name.
+func setMode(c *car.Car, name string) error {
+	m, err := control.ParseMode(name)
+	if err != nil {
+		return err
+	}
+	c.SetMode(m)
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/evaluate"
+	"github.com/hybridgroup/gophercar/tub"
+	"github.com/hybridgroup/gophercar/vision"
+)
+
+var (
+	evalOutput  string
+	evalSegment int
+	evalVideo   bool
+	evalScale   int
+	evalFPS     float64
+)
+
+var evalCmd = &command{
+	Name:  "eval",
+	Args:  "tub",
+	Short: "run the pilot over a recorded tub and report how far it is from the driver",
+	SetFlags: func(fs *flag.FlagSet) {
+		fs.StringVar(&cfg.Car.Model, "model", cfg.Car.Model, "model from gophercar train to evaluate, instead of the line pilot")
+		fs.Float64Var(&cfg.Car.Throttle, "throttle", cfg.Car.Throttle, "throttle of the line pilot")
+		fs.StringVar(&evalOutput, "o", "report", "directory to write the report, plots and video to")
+		fs.IntVar(&evalSegment, "segment", 200, "number of records in each plot")
+		fs.BoolVar(&evalVideo, "video", true, "write a video of the tub with the recorded and pilot steering and throttle")
+		fs.IntVar(&evalScale, "scale", 4, "how many times bigger than the tub images to make the video")
+		fs.Float64Var(&evalFPS, "fps", 20, "frame rate of the video")
+	},
+	Run: runEval,
+}
+
+func runEval(args []string) error {
+	if len(args) != 1 {
+		return usageError("eval needs a tub")
+	}
+	if evalSegment < 1 || evalScale < 1 {
+		return usageError("segment and scale must be at least 1")
+	}
+
+	t, err := tub.Open(args[0])
+	if err != nil {
+		return err
+	}
+	records, err := t.Records()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("tub %s has no records", t.Path)
+	}
+	if err := os.MkdirAll(evalOutput, 0755); err != nil {
+		return err
+	}
+
+	p, err := newPilot()
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+
+	var video *gocv.VideoWriter
+	frame := gocv.NewMat()
+	defer frame.Close()
+
+	results := make([]evaluate.Result, 0, len(records))
+	var last evaluate.Result
+	for _, r := range records {
+		img := gocv.IMRead(t.ImagePath(r), gocv.IMReadColor)
+		if img.Empty() {
+			img.Close()
+			return fmt.Errorf("reading the image of record %d", r.Index)
+		}
+
+		out := p.Run(img)
+		res := evaluate.Result{
+			Index:         r.Index,
+			Angle:         r.Angle,
+			Throttle:      r.Throttle,
+			Steering:      last.Steering,
+			PilotThrottle: last.PilotThrottle,
+			OK:            out.OK,
+		}
+		if out.OK {
+			res.Steering, res.PilotThrottle = out.Steering, out.Throttle
+		}
+		results = append(results, res)
+		last = res
+
+		if evalVideo {
+			size := image.Pt(img.Cols()*evalScale, img.Rows()*evalScale)
+			if video == nil {
+				name := filepath.Join(evalOutput, "replay.avi")
+				if video, err = gocv.VideoWriterFile(name, "MJPG", evalFPS, size.X, size.Y, true); err != nil {
+					img.Close()
+					return err
+				}
+				defer video.Close()
+			}
+			gocv.Resize(img, &frame, size, 0, 0, gocv.InterpolationLinear)
+			vision.DrawComparison(&frame, res.Angle, res.Throttle, res.Steering, res.PilotThrottle, res.OK)
+			if err := video.Write(frame); err != nil {
+				img.Close()
+				return err
+			}
+		}
+		img.Close()
+	}
+
+	pilotName := "line"
+	if cfg.Car.Model != "" {
+		pilotName = cfg.Car.Model
+	}
+	return writeReport(t.Path, pilotName, results)
+}
+
+// writeReport plots each segment of the results, and writes and prints the
+// errors.
+func writeReport(tubPath, pilotName string, results []evaluate.Result) error {
+	report := evaluate.Report{
+		Tub:      tubPath,
+		Pilot:    pilotName,
+		Errors:   evaluate.Compare(results),
+		Segments: evaluate.Segments(results, evalSegment),
+	}
+
+	start := 0
+	for i := range report.Segments {
+		s := &report.Segments[i]
+		s.Plot = fmt.Sprintf("segment-%03d.png", i)
+		if err := evaluate.Plot(results[start:start+s.Records], filepath.Join(evalOutput, s.Plot)); err != nil {
+			return err
+		}
+		start += s.Records
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(evalOutput, "report.json"), data, 0644); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s driven by %s: %d records, %d lost\n", tubPath, pilotName, report.Records, report.Lost)
+	fmt.Printf("  steering MAE %.3f, throttle MAE %.3f\n", report.SteeringMAE, report.ThrottleMAE)
+	for _, s := range report.Segments {
+		fmt.Printf("  %5d-%-5d steering MAE %.3f, throttle MAE %.3f, %d lost  %s\n", s.From, s.To, s.SteeringMAE, s.ThrottleMAE, s.Lost, s.Plot)
+	}
+	fmt.Println("Written to", evalOutput)
+	return nil
+}
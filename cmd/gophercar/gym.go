@@ -0,0 +1,174 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/gophercar/car"
+	"github.com/hybridgroup/gophercar/control"
+	"github.com/hybridgroup/gophercar/gym"
+	"github.com/hybridgroup/gophercar/gym/gymtest"
+	"github.com/hybridgroup/gophercar/runlog"
+)
+
+var (
+	gymAddress  string
+	gymScene    string
+	gymDuration time.Duration
+	gymMode     string
+	gymFake     bool
+	gymWeb      bool
+	gymDebug    bool
+)
+
+var gymCmd = &command{
+	Name:  "gym",
+	Short: "let the pilot drive in the Donkeycar simulator and report how it did",
+	SetFlags: func(fs *flag.FlagSet) {
+		fs.StringVar(&gymAddress, "sim", gym.DefaultAddress, "host:port of the simulator")
+		fs.StringVar(&gymScene, "scene", gym.DefaultScene, "track to load")
+		fs.DurationVar(&gymDuration, "duration", time.Minute, "how long to drive for")
+		fs.StringVar(&gymMode, "mode", string(control.Local), "drive mode: local to let the pilot steer and drive, or local_angle to only steer")
+		fs.Float64Var(&cfg.Car.Throttle, "throttle", cfg.Car.Throttle, "throttle of the pilot")
+		fs.StringVar(&cfg.Car.Model, "model", cfg.Car.Model, "model from gophercar train for the pilot to drive with, instead of following the line")
+		fs.BoolVar(&gymFake, "fake", false, "start a local stand-in for the simulator and drive in that")
+		fs.BoolVar(&gymWeb, "web", false, "serve the video stream and telemetry")
+		fs.BoolVar(&gymDebug, "debug", false, "stream each stage of the vision processing at /stage/<name>")
+		fs.StringVar(&cfg.Car.Address, "address", cfg.Car.Address, "host:port for the web server")
+	},
+	Run: runGym,
+}
+
+// gymScore sums up how well the car drove.
+type gymScore struct {
+	mu       sync.Mutex
+	frames   int
+	start    float64
+	time     float64
+	distance float64
+	sumCTE   float64
+	maxCTE   float64
+	hits     int
+	hit      bool
+}
+
+func (s *gymScore) add(t gym.Telemetry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.frames == 0 {
+		s.start = t.Time
+	} else {
+		s.distance += t.Speed * (t.Time - s.time)
+	}
+	s.frames++
+	s.time = t.Time
+
+	cte := math.Abs(t.CTE)
+	s.sumCTE += cte
+	s.maxCTE = math.Max(s.maxCTE, cte)
+
+	// count each collision once, however many frames it lasts
+	if t.Crashed() && !s.hit {
+		s.hits++
+	}
+	s.hit = t.Crashed()
+}
+
+func (s *gymScore) fields() runlog.Fields {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meanCTE := 0.0
+	if s.frames > 0 {
+		meanCTE = s.sumCTE / float64(s.frames)
+	}
+	return runlog.Fields{
+		"frames":   s.frames,
+		"time":     s.time - s.start,
+		"distance": s.distance,
+		"mean_cte": meanCTE,
+		"max_cte":  s.maxCTE,
+		"hits":     s.hits,
+	}
+}
+
+func runGym(args []string) error {
+	if len(args) > 0 {
+		return usageError("gym takes no arguments")
+	}
+
+	logger, err := runlog.Open(cfg.LogDir, "gym")
+	if err != nil {
+		return err
+	}
+	defer logger.Close()
+	logger.Info("start", runlog.Fields{
+		"command": "gym",
+		"args":    os.Args[1:],
+		"config":  cfg,
+	})
+
+	if gymFake {
+		server, err := gymtest.NewServer()
+		if err != nil {
+			return err
+		}
+		defer server.Close()
+		gymAddress = server.Addr
+		logger.Info("started a stand-in for the simulator at "+server.Addr, nil)
+	}
+
+	client, err := gym.Dial(gymAddress)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	score := &gymScore{}
+	client.OnTelemetry = score.add
+	if err := client.Load(gymScene, 30*time.Second); err != nil {
+		return err
+	}
+	logger.Info("loaded "+gymScene, nil)
+
+	c := car.New(cfg, client, logger)
+	defer c.Close()
+	c.Debug = gymDebug
+	c.Camera = client
+	if err := setPilot(c); err != nil {
+		return err
+	}
+	defer func() {
+		// stop the camera, and let the vision worker finish with the pilot
+		// before closing it
+		client.Close()
+		c.WaitCamera()
+		c.Pilot.Close()
+	}()
+
+	if err := setMode(c, gymMode); err != nil {
+		return err
+	}
+
+	if gymWeb {
+		go func() {
+			err := http.ListenAndServe(cfg.Car.Address, c.Handler())
+			logger.Error("web server stopped", runlog.Fields{"error": err.Error()})
+		}()
+		logger.Info("point your browser to "+cfg.Car.Address, nil)
+	}
+
+	c.Start()
+	time.Sleep(gymDuration)
+
+	client.SetSteering(0)
+	client.SetThrottle(0)
+	fields := score.fields()
+	logger.Info("done", fields)
+	fmt.Printf("Drove %.1fm in %.1fs, mean CTE %.3fm, max CTE %.3fm, %d collisions\n",
+		fields["distance"], fields["time"], fields["mean_cte"], fields["max_cte"], fields["hits"])
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+
+	"gobot.io/x/gobot"
+	"gobot.io/x/gobot/drivers/i2c"
+	"gobot.io/x/gobot/platforms/raspi"
+
+	"github.com/hybridgroup/gophercar/device"
+	"github.com/hybridgroup/gophercar/distance"
+	"github.com/hybridgroup/gophercar/imu"
+	"github.com/hybridgroup/gophercar/odometry"
+)
+
+// hardware is the Raspberry Pi and the I2C and GPIO devices of the car.
+type hardware struct {
+	adaptor *raspi.Adaptor
+	pca9685 *i2c.PCA9685Driver
+	mpu6050 *i2c.MPU6050Driver
+
+	// oled is nil unless the car was asked to show the dashboard.
+	oled *i2c.SSD1306Driver
+
+	// rangeDriver is the range sensor in the config, and rangeSensor the same
+	// with its errors retried and counted. Both are nil if the car has none.
+	rangeDriver gobot.Device
+	rangeSensor *device.Range
+
+	// encoder is the wheel encoder, or nil if the car has none.
+	encoder *odometry.EncoderDriver
+
+	// pwm and imu are the PCA9685 and MPU6050 with their errors retried and
+	// counted.
+	pwm *device.PWM
+	imu *device.Sensor
+
+	// started are the devices started by start or startDevice.
+	started []gobot.Device
+}
+
+func newHardware(withOLED bool) (*hardware, error) {
+	h := &hardware{adaptor: raspi.NewAdaptor()}
+	h.pca9685 = i2c.NewPCA9685Driver(h.adaptor)
+	h.mpu6050 = i2c.NewMPU6050Driver(h.adaptor)
+	if withOLED {
+		h.oled = i2c.NewSSD1306Driver(h.adaptor)
+	}
+
+	h.pwm = device.NewPWM(h.pca9685)
+	h.imu = device.NewSensor("mpu6050", imu.NewMPU6050(h.mpu6050, cfg.IMU))
+
+	switch cfg.Car.RangeSensor {
+	case "":
+	case "hcsr04":
+		d := distance.NewHCSR04Driver(h.adaptor, cfg.Car.TriggerPin, cfg.Car.EchoPin)
+		h.rangeDriver, h.rangeSensor = d, device.NewRange("hcsr04", d)
+	case "vl53l0x":
+		d := distance.NewVL53L0XDriver(h.adaptor)
+		h.rangeDriver, h.rangeSensor = d, device.NewRange("vl53l0x", d)
+	default:
+		return nil, fmt.Errorf("unknown range sensor %q, use hcsr04 or vl53l0x", cfg.Car.RangeSensor)
+	}
+
+	if cfg.Car.EncoderPin != "" {
+		h.encoder = odometry.NewEncoderDriver(h.adaptor, cfg.Car.EncoderPin)
+	}
+	return h, nil
+}
+
+func (h *hardware) connections() []gobot.Connection {
+	return []gobot.Connection{h.adaptor}
+}
+
+func (h *hardware) devices() []gobot.Device {
+	devices := []gobot.Device{h.pca9685, h.mpu6050}
+	if h.oled != nil {
+		devices = append(devices, h.oled)
+	}
+	if h.rangeDriver != nil {
+		devices = append(devices, h.rangeDriver)
+	}
+	if h.encoder != nil {
+		devices = append(devices, h.encoder)
+	}
+	return devices
+}
+
+// start connects to the Pi and starts each device, for the commands that do
+// not run a robot.
+func (h *hardware) start() error {
+	if err := h.adaptor.Connect(); err != nil {
+		return err
+	}
+	for _, d := range h.devices() {
+		if err := h.startDevice(d); err != nil {
+			return fmt.Errorf("starting %s: %v", d.Name(), err)
+		}
+	}
+	return nil
+}
+
+// startDevice starts a single device, once the Pi is connected.
+func (h *hardware) startDevice(d gobot.Device) error {
+	if err := d.Start(); err != nil {
+		return err
+	}
+	h.started = append(h.started, d)
+	return nil
+}
+
+// halt stops the started devices and disconnects from the Pi.
+func (h *hardware) halt() {
+	for _, d := range h.started {
+		d.Halt()
+	}
+	h.adaptor.Finalize()
+}
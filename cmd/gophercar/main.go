@@ -0,0 +1,139 @@
+// Command gophercar drives a Gophercar, records driving data and tests its
+// hardware, much like the Donkeycar manage.py.
+//
+// How to run:
+//
+//	gophercar [-config file] <command> [flags] [args]
+//
+// The commands are:
+//
+//	drive        drive with a joystick or the keyboard
+//	record       drive and record the camera, steering and throttle to a tub
+//	autopilot    let the pilot drive
+//	calibrate    find the PWM pulses of the servo and ESC, or calibrate the IMU or encoder
+//	test-hw      check that each part of the car is working
+//	test-vision  check the line follower against frames with known answers
+//	replay       stream a recorded tub with the HUD
+//	deploy       build the car, copy it to the Pi and run it as a systemd service
+//	sim          let the pilot drive laps of a simulated track
+//	gym          let the pilot drive in the Donkeycar simulator and report how it did
+//	train        train a model for the pilot from recorded tubs
+//	tub          inspect, clean, split and augment recorded tubs
+//	eval         run the pilot over a recorded tub and report how far it is from the driver
+//
+// Run "gophercar <command> -h" for the flags of a command. The settings of the
+// car are read from gophercar.json, or the file given with -config or the
+// GOPHERCAR_CONFIG environment variable.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hybridgroup/gophercar/config"
+)
+
+// command is a gophercar subcommand.
+type command struct {
+	Name  string
+	Args  string
+	Short string
+
+	// SetFlags adds the flags of the command to fs. It is called after the
+	// config is loaded, so that flags can default to, and override, the
+	// config.
+	SetFlags func(fs *flag.FlagSet)
+
+	// Run is called with the arguments left after parsing the flags.
+	Run func(args []string) error
+}
+
+var commands = []*command{
+	driveCmd,
+	recordCmd,
+	autopilotCmd,
+	calibrateCmd,
+	testHWCmd,
+	visionTestCmd,
+	replayCmd,
+	deployCmd,
+	simCmd,
+	gymCmd,
+	trainCmd,
+	tubCmd,
+	evalCmd,
+}
+
+var (
+	configPath = flag.String("config", config.Path(), "car config file")
+
+	// cfg is the config of the car, loaded before a command is run.
+	cfg *config.Config
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "gophercar: unknown command %q\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	cfg, err = config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gophercar: loading config %s: %v\n", *configPath, err)
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: gophercar %s [flags] %s\n\n%s.\n\nFlags:\n", cmd.Name, cmd.Args, cmd.Short)
+		fs.PrintDefaults()
+	}
+	if cmd.SetFlags != nil {
+		cmd.SetFlags(fs)
+	}
+	fs.Parse(flag.Args()[1:])
+
+	if err := cmd.Run(fs.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "gophercar %s: %v\n", cmd.Name, err)
+		if _, ok := err.(usageError); ok {
+			fs.Usage()
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: gophercar [-config file] <command> [flags] [args]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.Name, cmd.Short)
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// usageError is returned by a command run with the wrong arguments.
+type usageError string
+
+func (e usageError) Error() string { return string(e) }
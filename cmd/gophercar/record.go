@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/hybridgroup/gophercar/car"
+	"github.com/hybridgroup/gophercar/tub"
+)
+
+var (
+	recordOptions carOptions
+	recordTub     string
+)
+
+var recordCmd = &command{
+	Name:  "record",
+	Short: "drive and record the camera, steering and throttle to a tub",
+	SetFlags: func(fs *flag.FlagSet) {
+		recordOptions.setFlags(fs, "joystick")
+		fs.StringVar(&recordTub, "tub", "", "tub to record to, by default a new tub in the data_dir of the config")
+		fs.StringVar(&cfg.DataDir, "data", cfg.DataDir, "directory for new tubs")
+	},
+	Run: runRecord,
+}
+
+func runRecord(args []string) error {
+	if len(args) > 0 {
+		return usageError("record takes no arguments")
+	}
+
+	path := recordTub
+	if path == "" {
+		path = tub.NewPath(cfg.DataDir)
+	}
+	t, err := tub.Create(path)
+	if err != nil {
+		return err
+	}
+
+	return runCar("record", &recordOptions, func(c *car.Car) error {
+		if err := setPilot(c); err != nil {
+			return err
+		}
+		c.Record(t)
+		return nil
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hybridgroup/mjpeg"
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/telemetry"
+	"github.com/hybridgroup/gophercar/tub"
+	"github.com/hybridgroup/gophercar/vision"
+)
+
+var (
+	replaySpeed float64
+	replayLoop  bool
+)
+
+var replayCmd = &command{
+	Name:  "replay",
+	Args:  "tub",
+	Short: "stream a recorded tub with the HUD",
+	SetFlags: func(fs *flag.FlagSet) {
+		fs.Float64Var(&replaySpeed, "speed", 1, "how many times faster than it was recorded to replay")
+		fs.BoolVar(&replayLoop, "loop", false, "start again at the end of the tub")
+		fs.StringVar(&cfg.Car.Address, "address", cfg.Car.Address, "host:port for the web server")
+	},
+	Run: runReplay,
+}
+
+func runReplay(args []string) error {
+	if len(args) != 1 {
+		return usageError("replay needs a tub")
+	}
+	if replaySpeed <= 0 {
+		return usageError("speed must be more than 0")
+	}
+
+	t, err := tub.Open(args[0])
+	if err != nil {
+		return err
+	}
+	records, err := t.Records()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return fmt.Errorf("tub %s has no records", t.Path)
+	}
+
+	stream := mjpeg.NewStream()
+	tele := telemetry.New()
+	mux := http.NewServeMux()
+	mux.Handle("/", stream)
+	mux.Handle("/api/telemetry", tele)
+	go func() {
+		fmt.Println(http.ListenAndServe(cfg.Car.Address, mux))
+	}()
+	fmt.Printf("Replaying %d records from %s, point your browser to %s\n", len(records), t.Path, cfg.Car.Address)
+
+	for {
+		if err := replay(t, records, stream, tele); err != nil {
+			return err
+		}
+		if !replayLoop {
+			return nil
+		}
+	}
+}
+
+// replay streams each record of the tub, waiting between them as long as
+// when they were recorded.
+func replay(t *tub.Tub, records []tub.Record, stream *mjpeg.Stream, tele *telemetry.Telemetry) error {
+	start := time.Now()
+	first := records[0].Time()
+	for _, r := range records {
+		img := gocv.IMRead(t.ImagePath(r), gocv.IMReadColor)
+		if img.Empty() {
+			img.Close()
+			return fmt.Errorf("reading the image of record %d", r.Index)
+		}
+
+		tele.Update(func(d *telemetry.Data) {
+			d.Time = r.Time()
+			d.Mode = r.Mode
+			d.Steering = r.Angle
+			d.Throttle = r.Throttle
+			d.Records = r.Index
+			d.LineFound = true
+		})
+		vision.DrawHUD(&img, tele.Get())
+
+		// wait until the time of the record, scaled by the replay speed
+		due := start.Add(time.Duration(float64(r.Time().Sub(first)) / replaySpeed))
+		time.Sleep(time.Until(due))
+
+		buf, err := gocv.IMEncode(".jpg", img)
+		img.Close()
+		if err != nil {
+			return err
+		}
+		stream.UpdateJPEG(buf)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/hybridgroup/gophercar/car"
+	"github.com/hybridgroup/gophercar/control"
+	"github.com/hybridgroup/gophercar/distance/distancetest"
+	"github.com/hybridgroup/gophercar/imu"
+	"github.com/hybridgroup/gophercar/odometry"
+	"github.com/hybridgroup/gophercar/runlog"
+	"github.com/hybridgroup/gophercar/sim"
+)
+
+var (
+	simLaps     int
+	simTimeout  time.Duration
+	simTrack    string
+	simScale    float64
+	simStart    string
+	simMode     string
+	simObstacle float64
+	simWeb      bool
+	simDebug    bool
+)
+
+var simCmd = &command{
+	Name:  "sim",
+	Short: "let the pilot drive laps of a simulated track",
+	SetFlags: func(fs *flag.FlagSet) {
+		fs.IntVar(&simLaps, "laps", 2, "number of laps to drive")
+		fs.DurationVar(&simTimeout, "timeout", 5*time.Minute, "how long to wait for the laps")
+		fs.StringVar(&simTrack, "track", "", "PNG or JPEG top down map of the track, with a bright line on a darker floor (default an oval)")
+		fs.Float64Var(&simScale, "scale", 0.01, "size in metres of a pixel of the track map")
+		fs.StringVar(&simStart, "start", "", "x,y,heading of the start of the track map, in metres from its bottom left corner and degrees counterclockwise")
+		fs.StringVar(&simMode, "mode", string(control.Local), "drive mode: local to let the pilot steer and drive, or local_angle to only steer")
+		fs.Float64Var(&cfg.Car.Throttle, "throttle", cfg.Car.Throttle, "throttle of the pilot")
+		fs.Float64Var(&cfg.Cruise.Speed, "speed", cfg.Cruise.Speed, "speed in m/s to drive at with the simulated wheel encoder, instead of a fixed throttle")
+		fs.StringVar(&cfg.Car.Model, "model", cfg.Car.Model, "model from gophercar train for the pilot to drive with, instead of following the line")
+		fs.Float64Var(&simObstacle, "obstacle", 0, "put an obstacle this many metres along the track, for the car to stop in front of instead of driving laps")
+		fs.BoolVar(&simWeb, "web", false, "serve the video stream, telemetry and the state of the simulation at /api/sim")
+		fs.BoolVar(&simDebug, "debug", false, "stream each stage of the vision processing at /stage/<name>")
+		fs.StringVar(&cfg.Car.Address, "address", cfg.Car.Address, "host:port for the web server")
+	},
+	Run: runSim,
+}
+
+// runSim drives the car round the track until it has done the laps, or has
+// stopped in front of the obstacle, and fails if it leaves the track, crashes,
+// hits the obstacle or runs out of time, so that it can be run in CI.
+func runSim(args []string) error {
+	if len(args) > 0 {
+		return usageError("sim takes no arguments")
+	}
+
+	track, err := simulatedTrack()
+	if err != nil {
+		return err
+	}
+
+	logger, err := runlog.Open(cfg.LogDir, "sim")
+	if err != nil {
+		return err
+	}
+	defer logger.Close()
+	logger.Info("start", runlog.Fields{
+		"command": "sim",
+		"args":    os.Args[1:],
+		"config":  cfg,
+	})
+
+	s := sim.New(track)
+	go s.Run()
+
+	c := car.New(cfg, s, logger)
+	defer c.Close()
+	c.Debug = simDebug
+	c.Orientation = imu.New(s, time.Duration(cfg.Car.LoopInterval))
+	c.Camera = sim.NewFrames(s)
+	c.Odometer = odometry.NewOdometer(sim.NewEncoder(s, simEncoderPulses), 1/simEncoderPulses)
+	if err := setCruise(c); err != nil {
+		return err
+	}
+	if simObstacle > 0 {
+		// a range sensor that sees the obstacle along the track
+		c.Range = distancetest.Func(func() float64 {
+			return math.Min(simObstacle-s.Status().Distance, 4)
+		})
+	}
+	if err := setPilot(c); err != nil {
+		return err
+	}
+	defer func() {
+		// stop the camera, and let the vision worker finish with the pilot
+		// before closing it
+		s.Close()
+		c.WaitCamera()
+		c.Pilot.Close()
+	}()
+
+	if err := c.Orientation.CalibrateGyro(gyroSamples); err != nil {
+		return err
+	}
+	if err := setMode(c, simMode); err != nil {
+		return err
+	}
+
+	if simWeb {
+		mux := http.NewServeMux()
+		mux.Handle("/", c.Handler())
+		mux.HandleFunc("/api/sim", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(s.Status())
+		})
+		go func() {
+			err := http.ListenAndServe(cfg.Car.Address, mux)
+			logger.Error("web server stopped", runlog.Fields{"error": err.Error()})
+		}()
+		logger.Info("point your browser to "+cfg.Car.Address, nil)
+	}
+
+	c.Start()
+
+	timeout := time.After(simTimeout)
+	check := time.NewTicker(100 * time.Millisecond)
+	defer check.Stop()
+	laps := 0
+	for {
+		select {
+		case <-timeout:
+			st := s.Status()
+			return fmt.Errorf("timed out after %d of %d laps, %.1fm driven", len(st.Laps), simLaps, st.Distance)
+		case <-check.C:
+		}
+
+		st := s.Status()
+		for ; laps < len(st.Laps); laps++ {
+			logger.Info(fmt.Sprintf("lap %d: %.2fs", laps+1, st.Laps[laps].Seconds()), runlog.Fields{"lap": laps + 1, "time": st.Laps[laps].Seconds()})
+		}
+		switch {
+		case !st.OnTrack:
+			return fmt.Errorf("left the track at %.2f, %.2f after %d laps", st.Pose.X, st.Pose.Y, len(st.Laps))
+		case c.Stopped():
+			return fmt.Errorf("stopped by a crash after %d laps", len(st.Laps))
+		case simObstacle > 0 && st.Distance >= simObstacle:
+			return fmt.Errorf("hit the obstacle at %.2fm", simObstacle)
+		case simObstacle > 0 && st.Distance > 0 && st.Speed < 0.01:
+			logger.Info(fmt.Sprintf("stopped %.2fm before the obstacle", simObstacle-st.Distance), runlog.Fields{"status": st})
+			return nil
+		case len(st.Laps) >= simLaps:
+			logger.Info("done", runlog.Fields{"status": st})
+			return nil
+		}
+	}
+}
+
+// simEncoderPulses is the pulses per metre of the simulated encoder, giving
+// 20 pulses for each turn of the motor, geared 5:1 to a 110mm wheel.
+var simEncoderPulses = 1 / odometry.MetresPerPulse(0.11, 20, 5)
+
+// simulatedTrack returns the track given with -track, or an oval.
+func simulatedTrack() (*sim.Track, error) {
+	if simTrack == "" {
+		return sim.Oval(4, 1.5), nil
+	}
+	if simStart == "" {
+		return nil, usageError("a track map needs its start given with -start")
+	}
+	var x, y, heading float64
+	if _, err := fmt.Sscanf(simStart, "%g,%g,%g", &x, &y, &heading); err != nil {
+		return nil, usageError("start must be x,y,heading")
+	}
+	return sim.LoadTrack(simTrack, simScale, sim.Pose{X: x, Y: y, Heading: heading * math.Pi / 180})
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/fogleman/gg"
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/car"
+	"github.com/hybridgroup/gophercar/dashboard"
+	"github.com/hybridgroup/gophercar/imu"
+	"github.com/hybridgroup/gophercar/telemetry"
+)
+
+var (
+	testOLED     bool
+	testSteering bool
+	testFrame    string
+)
+
+var testHWCmd = &command{
+	Name:  "test-hw",
+	Short: "check that each part of the car is working",
+	SetFlags: func(fs *flag.FlagSet) {
+		fs.BoolVar(&testOLED, "oled", false, "also test the SSD1306 OLED display")
+		fs.BoolVar(&testSteering, "steering", true, "turn the steering left and right")
+		fs.StringVar(&testFrame, "frame", "/tmp/img.jpg", "where to save a frame from the camera")
+		fs.IntVar(&cfg.Car.Camera, "camera", cfg.Car.Camera, "ID of the camera to open")
+	},
+	Run: runTestHW,
+}
+
+// hwTest is a check of one part of the car.
+type hwTest struct {
+	name string
+	run  func(hw *hardware) error
+}
+
+func runTestHW(args []string) error {
+	if len(args) > 0 {
+		return usageError("test-hw takes no arguments")
+	}
+
+	tests := []hwTest{
+		{"PCA9685", testPCA9685},
+		{"MPU6050", testMPU6050},
+		{"camera", testCamera},
+	}
+	if testOLED {
+		tests = append(tests, hwTest{"SSD1306", testSSD1306})
+	}
+	if cfg.Car.RangeSensor != "" {
+		tests = append(tests, hwTest{"range", testRange})
+	}
+	if cfg.Car.EncoderPin != "" {
+		tests = append(tests, hwTest{"encoder", testEncoder})
+	}
+
+	hw, err := newHardware(testOLED)
+	if err != nil {
+		return err
+	}
+	if err := hw.adaptor.Connect(); err != nil {
+		return fmt.Errorf("connecting to the Raspberry Pi: %v", err)
+	}
+	defer hw.halt()
+
+	failed := 0
+	for _, t := range tests {
+		fmt.Printf("%-8s ", t.name)
+		if err := t.run(hw); err != nil {
+			fmt.Println("FAIL", err)
+			failed++
+			continue
+		}
+		fmt.Println("ok")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", failed, len(tests))
+	}
+	return nil
+}
+
+// testPCA9685 sends the stopped pulse to the ESC, and turns the steering to
+// each side and back to the centre.
+func testPCA9685(hw *hardware) error {
+	if err := hw.startDevice(hw.pca9685); err != nil {
+		return err
+	}
+	act := car.NewPCA9685(hw.pwm, cfg.Car)
+	if err := act.Init(); err != nil {
+		return err
+	}
+	if !testSteering {
+		return nil
+	}
+	for _, s := range []float64{-1, 1, 0} {
+		if err := act.SetSteering(s); err != nil {
+			return err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return nil
+}
+
+// testMPU6050 reads the MPU6050 and checks that it sees about 1g of gravity.
+func testMPU6050(hw *hardware) error {
+	if err := hw.startDevice(hw.mpu6050); err != nil {
+		return err
+	}
+
+	var sum float64
+	const samples = 10
+	var r imu.Reading
+	for i := 0; i < samples; i++ {
+		var err error
+		if r, err = hw.imu.Read(); err != nil {
+			return err
+		}
+		a := r.Accel
+		sum += math.Sqrt(a.X*a.X + a.Y*a.Y + a.Z*a.Z)
+		time.Sleep(time.Duration(cfg.Car.LoopInterval))
+	}
+
+	g := sum / samples
+	if g < 0.8 || g > 1.2 {
+		return fmt.Errorf("accelerometer reads %.2fg standing still, expected 1g", g)
+	}
+	fmt.Printf("%.2fg %.0fC ", g, r.Temperature)
+	return nil
+}
+
+// testCamera reads a frame from the camera and saves it.
+func testCamera(hw *hardware) error {
+	webcam, err := gocv.OpenVideoCapture(cfg.Car.Camera)
+	if err != nil {
+		return err
+	}
+	defer webcam.Close()
+
+	img := gocv.NewMat()
+	defer img.Close()
+	if ok := webcam.Read(&img); !ok || img.Empty() {
+		return errors.New("no frame from the camera")
+	}
+	if !gocv.IMWrite(testFrame, img) {
+		return fmt.Errorf("saving a frame to %s", testFrame)
+	}
+	fmt.Printf("%dx%d saved to %s ", img.Cols(), img.Rows(), testFrame)
+	return nil
+}
+
+// testRange reads the range sensor a few times.
+func testRange(hw *hardware) error {
+	if err := hw.startDevice(hw.rangeDriver); err != nil {
+		return err
+	}
+	var sum float64
+	const samples = 5
+	for i := 0; i < samples; i++ {
+		d, err := hw.rangeSensor.Distance()
+		if err != nil {
+			return err
+		}
+		sum += d
+		time.Sleep(100 * time.Millisecond)
+	}
+	fmt.Printf("%s %.2fm ", cfg.Car.RangeSensor, sum/samples)
+	return nil
+}
+
+// testEncoder counts the encoder pulses while the wheels are turned by hand.
+func testEncoder(hw *hardware) error {
+	if err := hw.startDevice(hw.encoder); err != nil {
+		return err
+	}
+	fmt.Print("turn a wheel... ")
+	time.Sleep(3 * time.Second)
+	if hw.encoder.Count() == 0 {
+		return fmt.Errorf("no pulses on pin %s", cfg.Car.EncoderPin)
+	}
+	fmt.Printf("%d pulses ", hw.encoder.Count())
+	return nil
+}
+
+// testSSD1306 shows a dashboard page on the OLED display.
+func testSSD1306(hw *hardware) error {
+	oled := hw.oled
+	if err := hw.startDevice(oled); err != nil {
+		return err
+	}
+	ctx := gg.NewContext(oled.Buffer.Width, oled.Buffer.Height)
+	dash := dashboard.New()
+	dash.Render(ctx, telemetry.Data{Mode: "test-hw"})
+	return oled.ShowImage(ctx.Image())
+}
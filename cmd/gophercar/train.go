@@ -0,0 +1,188 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	_ "image/jpeg" // so tub images can be decoded
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/hybridgroup/gophercar/car"
+	"github.com/hybridgroup/gophercar/control"
+	"github.com/hybridgroup/gophercar/model"
+	"github.com/hybridgroup/gophercar/tub"
+	"github.com/hybridgroup/gophercar/vision"
+)
+
+var (
+	trainOutput   string
+	trainEpochs   int
+	trainPatience int
+	trainBatch    int
+	trainRate     float64
+	trainVal      float64
+	trainSeed     int64
+	trainInput    = model.Input{
+		Width:   car.RecordSize.X / 2,
+		Height:  int(float64(car.RecordSize.Y)*(1-vision.DefaultCropTop)) / 2,
+		CropTop: vision.DefaultCropTop,
+	}
+)
+
+var trainCmd = &command{
+	Name:  "train",
+	Args:  "tub...",
+	Short: "train a model for the pilot from recorded tubs",
+	SetFlags: func(fs *flag.FlagSet) {
+		fs.StringVar(&trainOutput, "o", filepath.Join("models", "pilot.json"), "file to save the model to")
+		fs.IntVar(&trainEpochs, "epochs", 50, "most epochs to train for")
+		fs.IntVar(&trainPatience, "patience", 5, "stop after this many epochs without the validation loss getting better")
+		fs.IntVar(&trainBatch, "batch", 64, "batch size")
+		fs.Float64Var(&trainRate, "rate", 0.001, "learning rate")
+		fs.Float64Var(&trainVal, "val", 0.2, "fraction of the records kept back for validation")
+		fs.Int64Var(&trainSeed, "seed", 1, "seed for the initial weights and the shuffling")
+		fs.IntVar(&trainInput.Width, "width", trainInput.Width, "width of the model input")
+		fs.IntVar(&trainInput.Height, "height", trainInput.Height, "height of the model input")
+		fs.Float64Var(&trainInput.CropTop, "crop", trainInput.CropTop, "fraction of the image cropped off the top")
+	},
+	Run: runTrain,
+}
+
+// runTrain trains a model on the records driven by the user, keeping the one
+// with the lowest validation loss.
+func runTrain(args []string) error {
+	if len(args) == 0 {
+		return usageError("train needs at least one tub")
+	}
+	if trainVal <= 0 || trainVal >= 1 {
+		return usageError("val must be between 0 and 1")
+	}
+	if trainEpochs < 1 {
+		return usageError("epochs must be at least 1")
+	}
+	if trainBatch < 1 {
+		return usageError("batch must be at least 1")
+	}
+
+	samples, err := loadSamples(args, trainInput)
+	if err != nil {
+		return err
+	}
+	rand.New(rand.NewSource(trainSeed)).Shuffle(len(samples), func(i, j int) {
+		samples[i], samples[j] = samples[j], samples[i]
+	})
+	nVal := int(float64(len(samples)) * trainVal)
+	if nVal == 0 || nVal == len(samples) {
+		return fmt.Errorf("%d records are not enough to train with", len(samples))
+	}
+	val, train := samples[:nVal], samples[nVal:]
+	fmt.Printf("Training on %d records, validating on %d\n", len(train), len(val))
+
+	m := model.New(trainInput, trainSeed)
+	t := model.NewTrainer(m, trainSeed)
+	t.BatchSize = trainBatch
+	t.LearningRate = trainRate
+
+	var best *model.Model
+	for epoch := 1; epoch <= trainEpochs; epoch++ {
+		loss := t.Epoch(train)
+		valLoss := m.Evaluate(val)
+		fmt.Printf("epoch %d: loss %.5f, validation loss %.5f\n", epoch, loss, valLoss)
+
+		if best == nil || valLoss < best.Loss {
+			m.Epochs, m.Loss = epoch, valLoss
+			best = m.Clone()
+		} else if epoch-best.Epochs >= trainPatience {
+			fmt.Printf("No better for %d epochs, stopping\n", trainPatience)
+			break
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(trainOutput), 0755); err != nil {
+		return err
+	}
+	if err := best.Save(trainOutput); err != nil {
+		return err
+	}
+	fmt.Printf("Saved the model from epoch %d, with validation loss %.5f, to %s\n", best.Epochs, best.Loss, trainOutput)
+	return nil
+}
+
+// loadSamples reads the records of the tubs that were driven by the user,
+// and makes their images into inputs.
+func loadSamples(paths []string, in model.Input) ([]model.Sample, error) {
+	type job struct {
+		t *tub.Tub
+		r tub.Record
+	}
+	var jobs []job
+	for _, path := range paths {
+		t, err := tub.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		records, err := t.Records()
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range records {
+			// the pilot should not learn from itself
+			if r.Mode == "" || r.Mode == string(control.User) {
+				jobs = append(jobs, job{t, r})
+			}
+		}
+	}
+
+	samples := make([]model.Sample, len(jobs))
+	errs := make(chan error, len(jobs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				j := jobs[i]
+				img, err := readImage(j.t.ImagePath(j.r))
+				if err != nil {
+					errs <- err
+					continue
+				}
+				samples[i] = model.Sample{
+					Input:    in.FromImage(img),
+					Steering: float32(j.r.Angle),
+					Throttle: float32(j.r.Throttle),
+				}
+			}
+		}()
+	}
+	for i := range jobs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	select {
+	case err := <-errs:
+		return nil, err
+	default:
+	}
+	return samples, nil
+}
+
+func readImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return img, nil
+}
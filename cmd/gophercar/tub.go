@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image/jpeg"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/hybridgroup/gophercar/augment"
+	"github.com/hybridgroup/gophercar/tub"
+)
+
+var (
+	tubVal     float64
+	tubChunk   int
+	tubSeed    int64
+	tubCopies  int
+	tubAugment augment.Options
+)
+
+var tubCmd = &command{
+	Name:  "tub",
+	Args:  "stats|rm|clean|split|augment tub [args]",
+	Short: "inspect, clean, split and augment recorded tubs",
+	SetFlags: func(fs *flag.FlagSet) {
+		fs.Float64Var(&tubVal, "val", 0.2, "split: fraction of the records for validation")
+		fs.IntVar(&tubChunk, "chunk", 100, "split: number of records kept together")
+		fs.Int64Var(&tubSeed, "seed", 1, "split and augment: random seed")
+		fs.IntVar(&tubCopies, "copies", 1, "augment: number of changed copies of each record")
+		fs.Float64Var(&tubAugment.Brightness, "brightness", 0.3, "augment: most the brightness is changed by, as a fraction")
+		fs.BoolVar(&tubAugment.Shadow, "shadow", true, "augment: add shadows")
+		fs.BoolVar(&tubAugment.Flip, "flip", true, "augment: mirror images and negate the steering")
+	},
+	Run: runTub,
+}
+
+var tubActions = map[string]func(args []string) error{
+	"stats":   tubStats,
+	"rm":      tubRemove,
+	"clean":   tubClean,
+	"split":   tubSplit,
+	"augment": tubAugmentCopy,
+}
+
+func runTub(args []string) error {
+	if len(args) < 2 {
+		return usageError("tub needs an action and a tub")
+	}
+	action, ok := tubActions[args[0]]
+	if !ok {
+		return usageError(fmt.Sprintf("unknown action %q", args[0]))
+	}
+	return action(args[1:])
+}
+
+// tubStats prints the stats of each tub.
+func tubStats(args []string) error {
+	for _, path := range args {
+		t, err := tub.Open(path)
+		if err != nil {
+			return err
+		}
+		records, err := t.Records()
+		if err != nil {
+			return err
+		}
+		s := tub.Summarize(records)
+
+		fmt.Printf("%s\n", t.Path)
+		fmt.Printf("  records   %d (%d to %d), %s\n", s.Records, s.First, s.Last, s.Duration)
+		var modes []string
+		for m, n := range s.Modes {
+			modes = append(modes, fmt.Sprintf("%s %d", m, n))
+		}
+		sort.Strings(modes)
+		fmt.Printf("  modes     %s\n", strings.Join(modes, ", "))
+		fmt.Printf("  stopped   %d\n", s.ZeroThrottle)
+		fmt.Printf("  angle     min %.2f mean %.2f max %.2f\n", s.Angle.Min, s.Angle.Mean, s.Angle.Max)
+		fmt.Printf("  throttle  min %.2f mean %.2f max %.2f\n", s.Throttle.Min, s.Throttle.Mean, s.Throttle.Max)
+		fmt.Printf("  steering  left %v right\n", s.Steering)
+	}
+	return nil
+}
+
+// tubRemove deletes ranges of records, given as from-to or single indexes.
+func tubRemove(args []string) error {
+	if len(args) < 2 {
+		return usageError("rm needs a tub and the records to delete, such as 100-250")
+	}
+	var ranges []tub.Range
+	for _, arg := range args[1:] {
+		r, err := tub.ParseRange(arg)
+		if err != nil {
+			return usageError(err.Error())
+		}
+		ranges = append(ranges, r)
+	}
+
+	t, err := tub.Open(args[0])
+	if err != nil {
+		return err
+	}
+	n, err := t.DeleteIf(func(r tub.Record) bool {
+		for _, rng := range ranges {
+			if rng.Contains(r.Index) {
+				return true
+			}
+		}
+		return false
+	})
+	fmt.Printf("Deleted %d records\n", n)
+	return err
+}
+
+// tubClean deletes the records where the car is stopped.
+func tubClean(args []string) error {
+	for _, path := range args {
+		t, err := tub.Open(path)
+		if err != nil {
+			return err
+		}
+		n, err := t.DeleteIf(func(r tub.Record) bool { return r.Throttle == 0 })
+		fmt.Printf("%s: deleted %d records with zero throttle\n", t.Path, n)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tubSplit copies a tub into new training and validation tubs.
+func tubSplit(args []string) error {
+	if len(args) != 3 {
+		return usageError("split needs a tub, and the training and validation tubs to make")
+	}
+	src, err := tub.Open(args[0])
+	if err != nil {
+		return err
+	}
+	train, err := newTub(args[1])
+	if err != nil {
+		return err
+	}
+	val, err := newTub(args[2])
+	if err != nil {
+		return err
+	}
+
+	nTrain, nVal, err := tub.Split(src, train, val, tubVal, tubChunk, tubSeed)
+	fmt.Printf("Copied %d records to %s and %d to %s\n", nTrain, train.Path, nVal, val.Path)
+	return err
+}
+
+// tubAugmentCopy copies a tub to a new one, adding changed copies of each
+// record.
+func tubAugmentCopy(args []string) error {
+	if len(args) != 2 {
+		return usageError("augment needs a tub and the tub to make")
+	}
+	src, err := tub.Open(args[0])
+	if err != nil {
+		return err
+	}
+	dst, err := newTub(args[1])
+	if err != nil {
+		return err
+	}
+	records, err := src.Records()
+	if err != nil {
+		return err
+	}
+
+	rnd := rand.New(rand.NewSource(tubSeed))
+	var buf bytes.Buffer
+	for _, r := range records {
+		if _, err := dst.Copy(src, r); err != nil {
+			return err
+		}
+		img, err := readImage(src.ImagePath(r))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < tubCopies; i++ {
+			out, angle := augment.Apply(img, r.Angle, tubAugment, rnd)
+			buf.Reset()
+			if err := jpeg.Encode(&buf, out, nil); err != nil {
+				return err
+			}
+			a := r
+			a.Angle = angle
+			if _, err := dst.Write(a, buf.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	fmt.Printf("Wrote %d records to %s\n", len(records)*(1+tubCopies), dst.Path)
+	return nil
+}
+
+// newTub creates a tub that must not exist yet.
+func newTub(path string) (*tub.Tub, error) {
+	if _, err := tub.Open(path); err == nil {
+		return nil, fmt.Errorf("tub %s already exists", path)
+	}
+	return tub.Create(path)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fogleman/gg"
+	"gobot.io/x/gobot"
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/car"
+	"github.com/hybridgroup/gophercar/dashboard"
+	"github.com/hybridgroup/gophercar/imu"
+	"github.com/hybridgroup/gophercar/odometry"
+	"github.com/hybridgroup/gophercar/runlog"
+)
+
+// gyroSamples is the number of samples averaged for the gyro bias at start.
+const gyroSamples = 100
+
+// carOptions are the flags shared by the commands that drive the car.
+type carOptions struct {
+	controller string
+	oled       bool
+	hud        bool
+	raw        bool
+	debug      bool
+	metrics    bool
+}
+
+// setFlags adds the flags of the options to fs. The camera, address and model
+// override the car config.
+func (o *carOptions) setFlags(fs *flag.FlagSet, controller string) {
+	fs.StringVar(&o.controller, "controller", controller, "how to drive: joystick, keyboard or none")
+	fs.BoolVar(&o.oled, "oled", false, "show the dashboard on the SSD1306 OLED display")
+	fs.BoolVar(&o.hud, "hud", true, "draw the telemetry HUD over the video stream")
+	fs.BoolVar(&o.raw, "raw", false, "also stream the camera video without annotations at /raw")
+	fs.BoolVar(&o.debug, "debug", false, "stream each stage of the vision processing at /stage/<name>")
+	fs.BoolVar(&o.metrics, "metrics", true, "serve Prometheus metrics at /metrics")
+	fs.IntVar(&cfg.Car.Camera, "camera", cfg.Car.Camera, "ID of the camera to open")
+	fs.StringVar(&cfg.Car.Address, "address", cfg.Car.Address, "host:port for the web server")
+	fs.StringVar(&cfg.Car.Model, "model", cfg.Car.Model, "model from gophercar train for the pilot to drive with, instead of following the line")
+}
+
+// runCar sets up the car hardware and runs the car until it is stopped.
+// setup is called with the car before it starts, to set the pilot or start
+// recording.
+func runCar(name string, o *carOptions, setup func(c *car.Car) error) error {
+	ctrl, err := newController(o.controller)
+	if err != nil {
+		return err
+	}
+
+	logger, err := runlog.Open(cfg.LogDir, name)
+	if err != nil {
+		return err
+	}
+	defer logger.Close()
+	logger.Info("start", runlog.Fields{
+		"command":    name,
+		"args":       os.Args[1:],
+		"controller": o.controller,
+		"config":     cfg,
+	})
+
+	hw, err := newHardware(o.oled)
+	if err != nil {
+		return err
+	}
+	act := car.NewPCA9685(hw.pwm, cfg.Car)
+	c := car.New(cfg, act, logger)
+	defer c.Close()
+	c.HUD = o.hud
+	c.Raw = o.raw
+	c.Debug = o.debug
+	c.Metrics = o.metrics
+	c.AddDevice(hw.pwm.Monitor)
+	c.AddDevice(hw.imu.Monitor)
+	c.Orientation = imu.New(hw.imu, time.Duration(cfg.Car.LoopInterval))
+	if hw.rangeSensor != nil {
+		c.AddDevice(hw.rangeSensor.Monitor)
+		c.Range = hw.rangeSensor
+	}
+	if hw.encoder != nil {
+		if cfg.Car.EncoderPulsesPerMetre <= 0 {
+			return errors.New("the encoder needs calibrating, run gophercar calibrate encoder")
+		}
+		c.Odometer = odometry.NewOdometer(hw.encoder, 1/cfg.Car.EncoderPulsesPerMetre)
+	}
+
+	webcam, err := gocv.OpenVideoCapture(cfg.Car.Camera)
+	if err != nil {
+		return err
+	}
+	defer webcam.Close()
+	c.Camera = webcam
+
+	if setup != nil {
+		if err := setup(c); err != nil {
+			return err
+		}
+	}
+	if c.Pilot != nil {
+		defer c.Pilot.Close()
+	}
+	if err := setCruise(c); err != nil {
+		return err
+	}
+	if err := setDetector(c); err != nil {
+		return err
+	}
+	if c.Detector != nil {
+		defer c.Detector.Close()
+	}
+
+	var dash *dashboard.Dashboard
+	var ctx *gg.Context
+	showDashboard := func() {}
+	if o.oled {
+		oled := hw.oled
+		dash = dashboard.New()
+		ctx = gg.NewContext(oled.Buffer.Width, oled.Buffer.Height)
+		// called from both the button handler and the ticker, which would
+		// otherwise draw into the same context at once
+		var mu sync.Mutex
+		showDashboard = func() {
+			mu.Lock()
+			defer mu.Unlock()
+			dash.Render(ctx, c.Telemetry.Get())
+			oled.ShowImage(ctx.Image())
+		}
+	}
+	nextPage := func() {
+		if dash != nil {
+			dash.Next()
+			showDashboard()
+		}
+	}
+
+	work := func() {
+		// init the PWM controller and the ESC for throttle zero
+		if err := act.Init(); err != nil {
+			logger.Error("initialising the PCA9685 failed", runlog.Fields{"error": err.Error()})
+		}
+		time.Sleep(300 * time.Millisecond)
+
+		// measure the gyro bias while we are still standing still
+		if err := c.Orientation.CalibrateGyro(gyroSamples); err != nil {
+			logger.Error("gyro calibration failed", runlog.Fields{"error": err.Error()})
+		} else {
+			logger.Info("gyro calibrated", runlog.Fields{"bias": c.Orientation.GyroBias})
+		}
+
+		c.Start()
+		ctrl.start(c, nextPage)
+		gobot.Every(1*time.Second, showDashboard)
+	}
+
+	robot := gobot.NewRobot("gophercar",
+		append(hw.connections(), ctrl.connections()...),
+		append(hw.devices(), ctrl.devices()...),
+		work,
+	)
+
+	handler := c.Handler()
+	go func() {
+		err := http.ListenAndServe(cfg.Car.Address, handler)
+		logger.Error("web server stopped", runlog.Fields{"error": err.Error()})
+		os.Exit(1)
+	}()
+	logger.Info("point your browser to "+cfg.Car.Address, nil)
+
+	return robot.Start()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hybridgroup/gophercar/vision/visiontest"
+)
+
+var (
+	visionTestDir       string
+	visionTestUpdate    bool
+	visionTestTolerance float64
+)
+
+var visionTestCmd = &command{
+	Name:  "test-vision",
+	Short: "check the line follower against frames with known answers",
+	SetFlags: func(fs *flag.FlagSet) {
+		fs.StringVar(&visionTestDir, "dir", "vision/testdata", "directory with the cases.json and frames")
+		fs.BoolVar(&visionTestUpdate, "update", false, "write the golden images from the output of the line follower")
+		fs.Float64Var(&visionTestTolerance, "tolerance", visiontest.DefaultTolerance, "largest mean difference from the golden images")
+	},
+	Run: runVisionTest,
+}
+
+func runVisionTest(args []string) error {
+	if len(args) > 0 {
+		return usageError("test-vision takes no arguments")
+	}
+
+	cases, err := visiontest.Load(visionTestDir)
+	if err != nil {
+		return err
+	}
+	results, err := visiontest.Run(visionTestDir, cases, visionTestTolerance, visionTestUpdate)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		status := "ok  "
+		if !r.OK() {
+			status = "FAIL"
+			failed++
+		}
+		line := "lost"
+		if r.Found {
+			line = fmt.Sprintf("%+.3f", r.Steering)
+		}
+		diff := ""
+		if r.Diff >= 0 {
+			diff = fmt.Sprintf("  diff %.2f", r.Diff)
+		}
+		fmt.Printf("%s %-24s %s%s\n", status, r.Case.Name, line, diff)
+		for _, f := range r.Failures {
+			fmt.Printf("       %s\n", f)
+		}
+	}
+
+	if visionTestUpdate {
+		fmt.Printf("Wrote %d golden images to %s/golden, review them before committing\n", len(results), visionTestDir)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cases failed", failed, len(results))
+	}
+	fmt.Printf("All %d cases passed\n", len(results))
+	return nil
+}
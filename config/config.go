@@ -0,0 +1,252 @@
+// Package config loads and saves the settings for a car, such as the sensor
+// calibration, so that they survive between runs.
+package config
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/hybridgroup/gophercar/control"
+	"github.com/hybridgroup/gophercar/imu"
+)
+
+// DefaultPath is the config file used when the GOPHERCAR_CONFIG environment
+// variable is not set.
+const DefaultPath = "gophercar.json"
+
+// Config is the saved configuration of a car.
+type Config struct {
+	Car Car `json:"car"`
+
+	IMU imu.Calibration `json:"imu"`
+
+	// Stabilizer is the yaw rate steering loop.
+	Stabilizer control.StabilizerConfig `json:"stabilizer"`
+
+	// Cruise is the speed loop that drives the pilot at a target speed
+	// measured by the wheel encoder.
+	Cruise control.CruiseConfig `json:"cruise"`
+
+	// Collision is the emergency brake that slows the car down and stops it
+	// in front of obstacles seen by the range sensor.
+	Collision control.CollisionConfig `json:"collision"`
+
+	// Detect finds objects in the camera frames for the car to stop, wait or
+	// slow down for.
+	Detect Detect `json:"detect"`
+
+	// LogDir is where a JSON lines log file is written for each run, and
+	// DataDir is where tubs of recorded driving data are saved.
+	LogDir  string `json:"log_dir"`
+	DataDir string `json:"data_dir"`
+
+	Deploy Deploy `json:"deploy"`
+}
+
+// Car is the hardware setup of a car.
+type Car struct {
+	// Camera is the ID of the camera to open, and Address is the host:port
+	// the web server listens on.
+	Camera  int    `json:"camera"`
+	Address string `json:"address"`
+
+	// PCA9685 channels and PWM frequency in Hz for the steering servo and
+	// the ESC.
+	PWMFrequency    float32 `json:"pwm_frequency"`
+	SteeringChannel int     `json:"steering_channel"`
+	ThrottleChannel int     `json:"throttle_channel"`
+
+	// Pulses for full left and right steering, and for full forward,
+	// stopped and full reverse throttle. Use "gophercar calibrate steering"
+	// and "gophercar calibrate throttle" to find them.
+	SteeringLeftPulse    int `json:"steering_left_pulse"`
+	SteeringRightPulse   int `json:"steering_right_pulse"`
+	ThrottleForwardPulse int `json:"throttle_forward_pulse"`
+	ThrottleStoppedPulse int `json:"throttle_stopped_pulse"`
+	ThrottleReversePulse int `json:"throttle_reverse_pulse"`
+
+	// MaxThrottle scales the throttle from the driver, and Throttle is the
+	// throttle used by the autopilot, unless it drives at a target speed set
+	// in Cruise.
+	MaxThrottle float64 `json:"max_throttle"`
+	Throttle    float64 `json:"throttle"`
+
+	// Model is a model trained with "gophercar train" for the pilot to drive
+	// with, instead of following the line.
+	Model string `json:"model,omitempty"`
+
+	// RangeSensor is the distance sensor looking ahead for obstacles:
+	// "hcsr04", "vl53l0x", or "" for none. TriggerPin and EchoPin are the Pi
+	// header pins the HC-SR04 is connected to.
+	RangeSensor string `json:"range_sensor,omitempty"`
+	TriggerPin  string `json:"trigger_pin,omitempty"`
+	EchoPin     string `json:"echo_pin,omitempty"`
+
+	// EncoderPin is the Pi header pin of a hall effect sensor or encoder on a
+	// wheel or the motor, or "" for none, and EncoderPulsesPerMetre how many
+	// pulses it gives for each metre driven. Use "gophercar calibrate
+	// encoder" to find it.
+	EncoderPin            string  `json:"encoder_pin,omitempty"`
+	EncoderPulsesPerMetre float64 `json:"encoder_pulses_per_metre,omitempty"`
+
+	// LoopInterval is how often the drive loop reads the IMU and sets the
+	// steering and throttle.
+	LoopInterval Duration `json:"loop_interval"`
+}
+
+// Detect is the object detection setup. It is turned off unless Model or
+// Cascade is set.
+type Detect struct {
+	// Model is a MobileNet-SSD network, with ModelConfig its Caffe prototxt
+	// if it needs one, and Labels a file with the class names one per line.
+	Model       string `json:"model,omitempty"`
+	ModelConfig string `json:"model_config,omitempty"`
+	Labels      string `json:"labels,omitempty"`
+
+	// Cascade is a Haar cascade to use instead of a network, finding objects
+	// of CascadeClass.
+	Cascade      string `json:"cascade,omitempty"`
+	CascadeClass string `json:"cascade_class,omitempty"`
+
+	// Confidence is the lowest confidence from 0 to 1 of the objects found,
+	// and Every how many frames to run the detection on one of, as it is
+	// slow on a Pi.
+	Confidence float64 `json:"confidence"`
+	Every      int     `json:"every"`
+
+	// Rules are what the car does when it sees each class of object.
+	Rules []control.Rule `json:"rules"`
+}
+
+// Enabled returns true if a network or a cascade is set.
+func (d Detect) Enabled() bool {
+	return d.Model != "" || d.Cascade != ""
+}
+
+// Deploy is where and how "gophercar deploy" installs the car.
+type Deploy struct {
+	// Host is the host or host:port of the Pi, and User the user to log in
+	// as. Dir is the directory on the Pi the car is installed to.
+	Host string `json:"host"`
+	User string `json:"user"`
+	Dir  string `json:"dir"`
+
+	// Service is the name of the systemd service, and Run the gophercar
+	// command and flags it runs, such as "autopilot -throttle 0.2".
+	Service string `json:"service"`
+	Run     string `json:"run"`
+
+	// Files are extra files copied to Dir along with the config, such as
+	// models for the pilot.
+	Files []string `json:"files,omitempty"`
+
+	// The cross compiler for linux/arm, and the sysroot holding the Raspbian
+	// OpenCV headers and libraries for gocv.
+	GOARM   string `json:"goarm"`
+	CC      string `json:"cc"`
+	CXX     string `json:"cxx"`
+	Sysroot string `json:"sysroot,omitempty"`
+}
+
+// Duration is a time.Duration that is written to JSON as a string such as "10ms".
+type Duration time.Duration
+
+// MarshalJSON writes the Duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON reads a Duration from a string such as "10ms".
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Default returns the configuration used for a car that has no config file yet.
+func Default() *Config {
+	return &Config{
+		Car: Car{
+			Camera:               0,
+			Address:              "0.0.0.0:8080",
+			PWMFrequency:         60,
+			SteeringChannel:      1,
+			ThrottleChannel:      0,
+			SteeringLeftPulse:    290,
+			SteeringRightPulse:   490,
+			ThrottleForwardPulse: 300,
+			ThrottleStoppedPulse: 350,
+			ThrottleReversePulse: 490,
+			TriggerPin:           "16",
+			EchoPin:              "18",
+			MaxThrottle:          0.25,
+			Throttle:             0.2,
+			LoopInterval:         Duration(10 * time.Millisecond),
+		},
+		Stabilizer: control.DefaultStabilizerConfig(),
+		Cruise:     control.DefaultCruiseConfig(),
+		Collision:  control.DefaultCollisionConfig(),
+		Detect: Detect{
+			CascadeClass: "stop sign",
+			Confidence:   0.5,
+			Every:        3,
+			Rules:        control.DefaultRules(),
+		},
+		LogDir:  "logs",
+		DataDir: "data",
+		Deploy: Deploy{
+			User:    "pi",
+			Dir:     "/home/pi/gophercar",
+			Service: "gophercar",
+			Run:     "autopilot",
+			GOARM:   "7",
+			CC:      "arm-linux-gnueabihf-gcc",
+			CXX:     "arm-linux-gnueabihf-g++",
+		},
+	}
+}
+
+// Path returns the config file to use, from GOPHERCAR_CONFIG or DefaultPath.
+func Path() string {
+	if p := os.Getenv("GOPHERCAR_CONFIG"); p != "" {
+		return p
+	}
+	return DefaultPath
+}
+
+// Load reads the config file at path. If the file does not exist yet the
+// Default config is returned.
+func Load(path string) (*Config, error) {
+	c := Default()
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Save writes the config to the file at path.
+func (c *Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
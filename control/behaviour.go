@@ -0,0 +1,193 @@
+package control
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Action is what the car does when it sees an object.
+type Action string
+
+const (
+	// Stop stops the car for as long as the object is seen.
+	Stop Action = "stop"
+
+	// Wait stops the car for a while when the object is first seen, then
+	// ignores it so that the car can drive past, as at a stop sign.
+	Wait Action = "wait"
+
+	// Slow scales the throttle down for as long as the object is seen.
+	Slow Action = "slow"
+)
+
+// Rule is what to do when a class of object is seen.
+type Rule struct {
+	Class  string `json:"class"`
+	Action Action `json:"action"`
+
+	// MinConfidence is the lowest detection confidence from 0 to 1, and
+	// MinArea the smallest fraction of the frame the object has to fill,
+	// which is how close it has to be.
+	MinConfidence float64 `json:"min_confidence"`
+	MinArea       float64 `json:"min_area"`
+
+	// Throttle is the fraction of the throttle to drive at when slowing.
+	Throttle float64 `json:"throttle,omitempty"`
+
+	// Wait is how long to wait in seconds, and Ignore how long to ignore the
+	// object for afterwards.
+	Wait   float64 `json:"wait,omitempty"`
+	Ignore float64 `json:"ignore,omitempty"`
+
+	// Hold is how long in seconds to keep stopping or slowing after the
+	// object was last seen, so that a missed detection or two does not let
+	// the car go.
+	Hold float64 `json:"hold,omitempty"`
+}
+
+// DefaultRules wait at stop signs, stop for people and slow down behind other
+// cars, using the class names of the COCO dataset.
+func DefaultRules() []Rule {
+	return []Rule{
+		{Class: "stop sign", Action: Wait, MinConfidence: 0.5, MinArea: 0.01, Wait: 3, Ignore: 5},
+		{Class: "person", Action: Stop, MinConfidence: 0.5, MinArea: 0.02, Hold: 1},
+		{Class: "car", Action: Slow, MinConfidence: 0.5, MinArea: 0.02, Throttle: 0.5, Hold: 0.5},
+	}
+}
+
+// Sighting is an object seen in a camera frame.
+type Sighting struct {
+	Class      string
+	Confidence float64
+
+	// Area is the fraction of the frame the object fills.
+	Area float64
+}
+
+// BehaviourEvent is when the car starts or stops acting on a rule.
+type BehaviourEvent struct {
+	Time       time.Time `json:"time"`
+	Class      string    `json:"class"`
+	Action     Action    `json:"action"`
+	Confidence float64   `json:"confidence,omitempty"`
+	Started    bool      `json:"started"`
+}
+
+// Behaviour decides how the car reacts to the objects it sees, following
+// Rules. The most cautious of the rules being acted on wins. It is safe to
+// use from multiple goroutines.
+type Behaviour struct {
+	Rules []Rule
+
+	mu sync.Mutex
+
+	// state of each rule
+	until   []time.Time
+	ignored []time.Time
+	active  []bool
+}
+
+// NewBehaviour returns a Behaviour that follows rules.
+func NewBehaviour(rules []Rule) *Behaviour {
+	return &Behaviour{
+		Rules:   rules,
+		until:   make([]time.Time, len(rules)),
+		ignored: make([]time.Time, len(rules)),
+		active:  make([]bool, len(rules)),
+	}
+}
+
+// Update takes the objects seen in a frame at now, and returns the rules that
+// started or stopped being acted on.
+func (b *Behaviour) Update(seen []Sighting, now time.Time) []BehaviourEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var events []BehaviourEvent
+	for i, r := range b.Rules {
+		confidence := 0.0
+		for _, s := range seen {
+			if s.Class == r.Class && s.Confidence >= r.MinConfidence && s.Area >= r.MinArea && s.Confidence > confidence {
+				confidence = s.Confidence
+			}
+		}
+
+		if confidence > 0 && now.After(b.ignored[i]) {
+			switch r.Action {
+			case Wait:
+				// the wait starts when the object is first seen, and is
+				// not made longer by seeing it again
+				if !b.active[i] {
+					b.until[i] = now.Add(seconds(r.Wait))
+					b.ignored[i] = now.Add(seconds(r.Wait + r.Ignore))
+				}
+			default:
+				b.until[i] = now.Add(seconds(r.Hold))
+			}
+		}
+
+		active := confidence > 0 && r.Action != Wait || now.Before(b.until[i])
+		if active != b.active[i] {
+			b.active[i] = active
+			events = append(events, BehaviourEvent{Time: now, Class: r.Class, Action: r.Action, Confidence: confidence, Started: active})
+		}
+	}
+	return events
+}
+
+// Throttle returns the throttle to drive at instead of throttle.
+func (b *Behaviour) Throttle(throttle float64) float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	scale := 1.0
+	for i, r := range b.Rules {
+		if !b.active[i] {
+			continue
+		}
+		switch r.Action {
+		case Stop, Wait:
+			return 0
+		case Slow:
+			if r.Throttle < scale {
+				scale = r.Throttle
+			}
+		}
+	}
+	return throttle * scale
+}
+
+// Status describes the rules being acted on, such as "wait: stop sign", or
+// returns "" if there are none.
+func (b *Behaviour) Status() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := ""
+	for i, r := range b.Rules {
+		if b.active[i] {
+			if status != "" {
+				status += ", "
+			}
+			status += fmt.Sprintf("%s: %s", r.Action, r.Class)
+		}
+	}
+	return status
+}
+
+// Reset stops acting on all rules.
+func (b *Behaviour) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := range b.Rules {
+		b.until[i] = time.Time{}
+		b.ignored[i] = time.Time{}
+		b.active[i] = false
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,148 @@
+package control
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBehaviour(t *testing.T) {
+	var (
+		none   []Sighting
+		stop   = []Sighting{{Class: "stop sign", Confidence: 0.9, Area: 0.05}}
+		person = []Sighting{{Class: "person", Confidence: 0.8, Area: 0.1}}
+		car    = []Sighting{{Class: "car", Confidence: 0.7, Area: 0.1}}
+		both   = []Sighting{person[0], car[0]}
+	)
+	type step struct {
+		at       float64
+		seen     []Sighting
+		throttle float64
+	}
+	tests := []struct {
+		name  string
+		rules []Rule
+		steps []step
+	}{
+		{
+			name: "stop sign",
+			steps: []step{
+				{0, stop, 0},
+				{1, stop, 0},
+				{2, none, 0},
+				{3.1, none, 1},
+			},
+		},
+		{
+			// seeing the sign again neither makes the wait longer nor
+			// starts a new one until the ignore time is over
+			name: "stop sign seen again while waiting and ignored",
+			steps: []step{
+				{0, stop, 0},
+				{2.9, stop, 0},
+				{3.1, stop, 1},
+				{5, stop, 1},
+				{7.9, stop, 1},
+				{8.1, stop, 0},
+				{11, stop, 0},
+				{11.2, stop, 1},
+			},
+		},
+		{
+			name: "person held between detections",
+			steps: []step{
+				{0, person, 0},
+				{0.5, none, 0},
+				{1.1, none, 1},
+				{2, person, 0},
+				{2.9, none, 0},
+				{3.1, none, 1},
+			},
+		},
+		{
+			name: "car slows",
+			steps: []step{
+				{0, car, 0.5},
+				{0.4, none, 0.5},
+				{0.6, none, 1},
+			},
+		},
+		{
+			name: "most cautious rule wins",
+			steps: []step{
+				{0, both, 0},
+				{0.8, car, 0},
+				{1.2, car, 0.5},
+				{1.8, none, 1},
+			},
+		},
+		{
+			name: "slowest of two slow rules",
+			rules: []Rule{
+				{Class: "car", Action: Slow, Throttle: 0.5},
+				{Class: "person", Action: Slow, Throttle: 0.3},
+			},
+			steps: []step{
+				{0, car, 0.5},
+				{1, both, 0.3},
+				{2, person, 0.3},
+				{3, none, 1},
+			},
+		},
+		{
+			name: "not sure enough or too far away",
+			steps: []step{
+				{0, []Sighting{{Class: "person", Confidence: 0.4, Area: 0.1}}, 1},
+				{1, []Sighting{{Class: "person", Confidence: 0.9, Area: 0.01}}, 1},
+				{2, []Sighting{{Class: "dog", Confidence: 0.9, Area: 0.5}}, 1},
+			},
+		},
+	}
+
+	start := time.Date(2018, 8, 28, 10, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		rules := tt.rules
+		if rules == nil {
+			rules = DefaultRules()
+		}
+		b := NewBehaviour(rules)
+		for _, s := range tt.steps {
+			b.Update(s.seen, start.Add(seconds(s.at)))
+			if got := b.Throttle(1); !near(got, s.throttle, 1e-9) {
+				t.Errorf("%s: at %vs got throttle %v, want %v", tt.name, s.at, got, s.throttle)
+			}
+		}
+	}
+}
+
+func TestBehaviourEvents(t *testing.T) {
+	b := NewBehaviour(DefaultRules())
+	start := time.Date(2018, 8, 28, 10, 0, 0, 0, time.UTC)
+	person := []Sighting{{Class: "person", Confidence: 0.8, Area: 0.1}}
+	car := []Sighting{{Class: "car", Confidence: 0.7, Area: 0.1}}
+
+	events := b.Update(append(person, car...), start)
+	if len(events) != 2 || !events[0].Started || events[0].Class != "person" || events[0].Confidence != 0.8 || !events[1].Started || events[1].Class != "car" {
+		t.Errorf("when first seen got events %+v", events)
+	}
+	if got, want := b.Status(), "stop: person, slow: car"; got != want {
+		t.Errorf("got status %q, want %q", got, want)
+	}
+
+	if events := b.Update(person, start.Add(100*time.Millisecond)); len(events) != 0 {
+		t.Errorf("while still acting got events %+v", events)
+	}
+
+	events = b.Update(nil, start.Add(1100*time.Millisecond))
+	if len(events) != 2 || events[0].Started || events[1].Started {
+		t.Errorf("when held long enough got events %+v", events)
+	}
+	if got := b.Status(); got != "" {
+		t.Errorf("got status %q after the events ended", got)
+	}
+
+	b.Update(person, start.Add(2*time.Second))
+	b.Reset()
+	if got := b.Throttle(0.6); got != 0.6 {
+		t.Errorf("after Reset got throttle %v, want 0.6", got)
+	}
+}
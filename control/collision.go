@@ -0,0 +1,87 @@
+package control
+
+import (
+	"sync"
+	"time"
+)
+
+// CollisionConfig holds the settings for a Collision.
+type CollisionConfig struct {
+	// StopDistance is the distance in metres to an obstacle ahead at which
+	// the car stops, and SlowDistance where it starts to slow down. In
+	// between the forward throttle is capped in proportion to the distance.
+	StopDistance float64 `json:"stop_distance"`
+	SlowDistance float64 `json:"slow_distance"`
+
+	// MaxAge is how old in seconds the latest distance may be before the car
+	// is stopped, as it can no longer see what is ahead.
+	MaxAge float64 `json:"max_age"`
+}
+
+// DefaultCollisionConfig returns a CollisionConfig that stops the car 30cm
+// from an obstacle when driving at the usual track speeds.
+func DefaultCollisionConfig() CollisionConfig {
+	return CollisionConfig{
+		StopDistance: 0.3,
+		SlowDistance: 1.5,
+		MaxAge:       0.5,
+	}
+}
+
+// Collision is an emergency brake that limits the throttle by the distance
+// to the nearest obstacle ahead. It is safe to use from multiple goroutines.
+type Collision struct {
+	Config CollisionConfig
+
+	mu       sync.Mutex
+	distance float64
+	read     time.Time
+}
+
+// NewCollision returns a new Collision. It stops the car until it is given
+// the first distance.
+func NewCollision(c CollisionConfig) *Collision {
+	return &Collision{Config: c}
+}
+
+// Update sets the distance in metres to the nearest obstacle, measured at t.
+func (c *Collision) Update(distance float64, t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.distance = distance
+	c.read = t
+}
+
+// Distance returns the latest distance and when it was measured.
+func (c *Collision) Distance() (float64, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.distance, c.read
+}
+
+// Scale returns the fraction from 0 to 1 of the forward throttle that may be
+// used at now.
+func (c *Collision) Scale(now time.Time) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.read.IsZero() || now.Sub(c.read).Seconds() > c.Config.MaxAge {
+		return 0
+	}
+	if c.Config.SlowDistance <= c.Config.StopDistance {
+		if c.distance <= c.Config.StopDistance {
+			return 0
+		}
+		return 1
+	}
+	return clamp((c.distance-c.Config.StopDistance)/(c.Config.SlowDistance-c.Config.StopDistance), 0, 1)
+}
+
+// Limit returns throttle capped for the distance to the nearest obstacle at
+// now. Reversing away from the obstacle is not limited.
+func (c *Collision) Limit(throttle float64, now time.Time) float64 {
+	if throttle <= 0 {
+		return throttle
+	}
+	return throttle * c.Scale(now)
+}
@@ -0,0 +1,72 @@
+package control
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCollision(t *testing.T) {
+	start := time.Date(2018, 8, 28, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		distance float64
+		age      float64
+		throttle float64
+		want     float64
+	}{
+		{"clear", 3, 0, 0.8, 0.8},
+		{"at the slow distance", 1.5, 0, 0.8, 0.8},
+		{"half way", 0.9, 0, 0.8, 0.4},
+		{"at the stop distance", 0.3, 0, 0.8, 0},
+		{"too close", 0.1, 0, 0.8, 0},
+		{"recent enough", 3, 0.4, 0.8, 0.8},
+		{"stale", 3, 0.6, 0.8, 0},
+		{"reversing away", 0.1, 0, -0.5, -0.5},
+		{"reversing when stale", 3, 2, -0.5, -0.5},
+		{"stopped", 0.1, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		c := NewCollision(DefaultCollisionConfig())
+		c.Update(tt.distance, start)
+		now := start.Add(seconds(tt.age))
+		if got := c.Limit(tt.throttle, now); !near(got, tt.want, 1e-9) {
+			t.Errorf("%s: got throttle %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCollisionNoReading(t *testing.T) {
+	c := NewCollision(DefaultCollisionConfig())
+	now := time.Now()
+	if got := c.Scale(now); got != 0 {
+		t.Errorf("with no distance yet got scale %v, want 0", got)
+	}
+	if got := c.Limit(0.5, now); got != 0 {
+		t.Errorf("with no distance yet got throttle %v, want 0", got)
+	}
+	if got := c.Limit(-0.5, now); got != -0.5 {
+		t.Errorf("with no distance yet got reverse throttle %v, want -0.5", got)
+	}
+}
+
+func TestCollisionScale(t *testing.T) {
+	start := time.Date(2018, 8, 28, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		config   CollisionConfig
+		distance float64
+		want     float64
+	}{
+		{"default far", DefaultCollisionConfig(), 2, 1},
+		{"default near", DefaultCollisionConfig(), 0.6, 0.25},
+		{"no slowing, clear", CollisionConfig{StopDistance: 0.5, SlowDistance: 0.5, MaxAge: 1}, 0.51, 1},
+		{"no slowing, close", CollisionConfig{StopDistance: 0.5, SlowDistance: 0.5, MaxAge: 1}, 0.5, 0},
+	}
+	for _, tt := range tests {
+		c := NewCollision(tt.config)
+		c.Update(tt.distance, start)
+		if got := c.Scale(start); !near(got, tt.want, 1e-9) {
+			t.Errorf("%s: got scale %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Package control contains the controllers that sit between what the driver or
+// pilot asks for and what is sent to the steering servo and the ESC.
+package control
+
+import "fmt"
+
+// Mode is the drive mode of the car, following the Donkeycar names.
+type Mode string
+
+const (
+	// User mode is when steering and throttle both come from the user.
+	User Mode = "user"
+
+	// LocalAngle mode is when the pilot steers and the user controls the throttle.
+	LocalAngle Mode = "local_angle"
+
+	// Local mode is when the pilot controls both steering and throttle.
+	Local Mode = "local"
+)
+
+// Modes are the drive modes, in the order they are switched through.
+var Modes = []Mode{User, LocalAngle, Local}
+
+// ParseMode returns the Mode with the name s.
+func ParseMode(s string) (Mode, error) {
+	for _, m := range Modes {
+		if string(m) == s {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("unknown drive mode %q", s)
+}
+
+// Next returns the mode after m in Modes.
+func (m Mode) Next() Mode {
+	for i, mode := range Modes {
+		if mode == m {
+			return Modes[(i+1)%len(Modes)]
+		}
+	}
+	return User
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,76 @@
+package control
+
+import "time"
+
+// CruiseConfig holds the settings for a Cruise.
+type CruiseConfig struct {
+	// Speed is the speed in metres/second for the pilot to drive at, instead
+	// of its throttle. Cruise control is off when it is 0.
+	Speed float64 `json:"speed"`
+
+	// Feedforward is the throttle for each metre/second that the car about
+	// needs on the flat, which the loop corrects for the battery and slopes.
+	Feedforward float64 `json:"feedforward"`
+
+	// Kp, Ki and Kd are the gains of the speed loop, in throttle units per
+	// metre/second of error.
+	Kp float64 `json:"kp"`
+	Ki float64 `json:"ki"`
+	Kd float64 `json:"kd"`
+
+	// MaxThrottle is the most throttle the loop will use.
+	MaxThrottle float64 `json:"max_throttle"`
+}
+
+// DefaultCruiseConfig returns a CruiseConfig with gains that work for the
+// Exceed short course truck. Cruise control is off.
+func DefaultCruiseConfig() CruiseConfig {
+	return CruiseConfig{
+		Feedforward: 0.15,
+		Kp:          0.1,
+		Ki:          0.3,
+		Kd:          0,
+		MaxThrottle: 0.4,
+	}
+}
+
+// Cruise is a speed loop that sets the throttle to drive at a target speed
+// measured by a wheel encoder, whatever the battery voltage or slope.
+type Cruise struct {
+	Config CruiseConfig
+
+	pid  *PID
+	last time.Time
+}
+
+// NewCruise returns a new Cruise.
+func NewCruise(c CruiseConfig) *Cruise {
+	return &Cruise{
+		Config: c,
+		pid:    NewPID(c.Kp, c.Ki, c.Kd, -c.MaxThrottle, c.MaxThrottle),
+	}
+}
+
+// Update takes the target and the measured speed in metres/second at now, and
+// returns the throttle. A target of 0 stops the car and resets the loop.
+func (c *Cruise) Update(target, speed float64, now time.Time) float64 {
+	if target <= 0 {
+		c.Reset()
+		return 0
+	}
+
+	dt := 0.0
+	if !c.last.IsZero() {
+		dt = now.Sub(c.last).Seconds()
+	}
+	c.last = now
+
+	throttle := c.Config.Feedforward*target + c.pid.Update(target-speed, dt)
+	return clamp(throttle, 0, c.Config.MaxThrottle)
+}
+
+// Reset clears the loop state, for example after the car has stopped.
+func (c *Cruise) Reset() {
+	c.pid.Reset()
+	c.last = time.Time{}
+}
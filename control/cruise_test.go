@@ -0,0 +1,64 @@
+package control
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCruise(t *testing.T) {
+	type step struct {
+		at, target, speed float64
+		throttle          float64
+	}
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"at speed", []step{{0, 1, 1, 0.15}}},
+		{"too slow", []step{{0, 1, 0, 0.25}}},
+		{"clamped to the max throttle", []step{{0, 2, 0, 0.4}}},
+		{"too fast", []step{{0, 1, 3, 0}}},
+		{"stopped", []step{{0, 0, 1, 0}}},
+		{"negative target", []step{{0, -1, 0, 0}}},
+		{
+			name: "integral builds up",
+			steps: []step{
+				{0, 1, 0.5, 0.2},
+				{1, 1, 0.5, 0.35},
+				{2, 1, 1, 0.3},
+			},
+		},
+		{
+			// once stopped the loop starts again from nothing
+			name: "target of zero resets",
+			steps: []step{
+				{0, 1, 0.5, 0.2},
+				{1, 1, 0.5, 0.35},
+				{2, 0, 0.5, 0},
+				{3, 1, 0.5, 0.2},
+			},
+		},
+		{
+			name: "held back",
+			steps: []step{
+				{0, 1, 0, 0.25},
+				{5, 1, 0, 0.4},
+				{10, 1, 0, 0.4},
+			},
+		},
+	}
+
+	start := time.Date(2018, 8, 28, 10, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		c := NewCruise(DefaultCruiseConfig())
+		for i, s := range tt.steps {
+			got := c.Update(s.target, s.speed, start.Add(seconds(s.at)))
+			if !near(got, s.throttle, 1e-9) {
+				t.Errorf("%s: step %d: got throttle %.3f, want %.3f", tt.name, i, got, s.throttle)
+			}
+			if got < 0 || got > c.Config.MaxThrottle {
+				t.Errorf("%s: step %d: throttle %.3f is outside 0 to %.3f", tt.name, i, got, c.Config.MaxThrottle)
+			}
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package control
+
+// PID is a proportional-integral-derivative controller.
+type PID struct {
+	Kp, Ki, Kd float64
+
+	// Min and Max limit the output. The integral term is not allowed to wind up
+	// past them either.
+	Min, Max float64
+
+	integral float64
+	lastErr  float64
+	started  bool
+}
+
+// NewPID returns a new PID with the given gains and output limits.
+func NewPID(kp, ki, kd, min, max float64) *PID {
+	return &PID{Kp: kp, Ki: ki, Kd: kd, Min: min, Max: max}
+}
+
+// Update returns the controller output for the error between the setpoint and
+// the measured value, dt seconds after the previous call.
+func (p *PID) Update(err, dt float64) float64 {
+	var derivative float64
+	if p.started && dt > 0 {
+		derivative = (err - p.lastErr) / dt
+	}
+	p.lastErr = err
+	p.started = true
+
+	if p.Ki != 0 {
+		p.integral = clamp(p.integral+err*dt, p.Min/p.Ki, p.Max/p.Ki)
+	}
+
+	return clamp(p.Kp*err+p.Ki*p.integral+p.Kd*derivative, p.Min, p.Max)
+}
+
+// Reset clears the integral and derivative state.
+func (p *PID) Reset() {
+	p.integral = 0
+	p.lastErr = 0
+	p.started = false
+}
@@ -0,0 +1,68 @@
+package control
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestPID(t *testing.T) {
+	type step struct {
+		err, dt, want float64
+	}
+	tests := []struct {
+		name  string
+		pid   *PID
+		steps []step
+	}{
+		{
+			name:  "proportional",
+			pid:   NewPID(2, 0, 0, -1, 1),
+			steps: []step{{0.3, 0.1, 0.6}, {-0.2, 0.1, -0.4}},
+		},
+		{
+			name:  "output clamped",
+			pid:   NewPID(2, 0, 0, -1, 1),
+			steps: []step{{0.8, 0.1, 1}, {-3, 0.1, -1}},
+		},
+		{
+			name:  "integral",
+			pid:   NewPID(0, 1, 0, -1, 1),
+			steps: []step{{0.2, 1, 0.2}, {0.2, 1, 0.4}, {-0.1, 1, 0.3}},
+		},
+		{
+			// without anti-windup the integral would be 5 by the last step
+			// and the output would still be stuck at 1
+			name:  "no integral windup",
+			pid:   NewPID(0, 1, 0, -1, 1),
+			steps: []step{{1, 1, 1}, {1, 1, 1}, {1, 1, 1}, {1, 1, 1}, {1, 1, 1}, {-0.5, 1, 0.5}},
+		},
+		{
+			name:  "derivative after the first update",
+			pid:   NewPID(0, 0, 0.1, -1, 1),
+			steps: []step{{0.5, 0.1, 0}, {0.6, 0.1, 0.1}, {0.6, 0.1, 0}, {0.6, 0, 0}},
+		},
+	}
+	for _, tt := range tests {
+		for i, s := range tt.steps {
+			if got := tt.pid.Update(s.err, s.dt); !near(got, s.want, 1e-9) {
+				t.Errorf("%s: step %d: got %.3f, want %.3f", tt.name, i, got, s.want)
+			}
+		}
+	}
+}
+
+func TestPIDReset(t *testing.T) {
+	p := NewPID(0, 1, 1, -10, 10)
+	p.Update(2, 1)
+	p.Update(4, 1)
+	p.Reset()
+
+	// no integral or derivative is left over
+	if got := p.Update(1, 1); !near(got, 1, 1e-9) {
+		t.Errorf("after Reset got %.3f, want 1", got)
+	}
+}
@@ -0,0 +1,110 @@
+package control
+
+import (
+	"sync"
+	"time"
+)
+
+// StabilizerConfig holds the settings for a Stabilizer.
+type StabilizerConfig struct {
+	// Modes are the drive modes the stabilizer is used in. It is off in all
+	// other modes.
+	Modes []Mode `json:"modes"`
+
+	// MaxYawRate is the yaw rate in degrees/second the car turns at with full
+	// steering lock at normal driving speed.
+	MaxYawRate float64 `json:"max_yaw_rate"`
+
+	// Kp, Ki and Kd are the gains of the yaw rate loop, in steering units per
+	// degree/second of error.
+	Kp float64 `json:"kp"`
+	Ki float64 `json:"ki"`
+	Kd float64 `json:"kd"`
+}
+
+// DefaultStabilizerConfig returns a StabilizerConfig that works for the Exceed
+// short course truck. It is not enabled for any mode.
+func DefaultStabilizerConfig() StabilizerConfig {
+	return StabilizerConfig{
+		MaxYawRate: 180,
+		Kp:         0.004,
+		Ki:         0.002,
+		Kd:         0,
+	}
+}
+
+// Enabled returns true if the stabilizer should be used in the given mode.
+func (c StabilizerConfig) Enabled(mode Mode) bool {
+	for _, m := range c.Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// Stabilizer is an inner steering loop that uses the gyro to track the yaw
+// rate implied by the requested steering. This damps oscillation coming from
+// the outer loop, and adds steering when the car turns less than it should,
+// for example when the front wheels slip on a loose surface. It is safe to
+// use from multiple goroutines.
+type Stabilizer struct {
+	Config StabilizerConfig
+
+	// MaxInterval is the longest time between updates that the loop carries
+	// on over. After a longer gap, such as when the stabilizer was not used
+	// for a while, the loop starts again from nothing.
+	MaxInterval time.Duration
+
+	mu   sync.Mutex
+	pid  *PID
+	last time.Time
+}
+
+// NewStabilizer returns a new Stabilizer.
+func NewStabilizer(c StabilizerConfig) *Stabilizer {
+	return &Stabilizer{
+		Config:      c,
+		MaxInterval: 200 * time.Millisecond,
+		pid:         NewPID(c.Kp, c.Ki, c.Kd, -1, 1),
+	}
+}
+
+// Update takes the requested steering from -1.0 (hard left) to 1.0 (hard
+// right) and the measured yaw rate in degrees/second, counterclockwise
+// positive as reported by the IMU, and returns the corrected steering.
+func (s *Stabilizer) Update(steering, yawRate float64) float64 {
+	return s.update(steering, yawRate, time.Now())
+}
+
+func (s *Stabilizer) update(steering, yawRate float64, now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dt := 0.0
+	if !s.last.IsZero() {
+		dt = now.Sub(s.last).Seconds()
+	}
+	s.last = now
+	if dt > s.MaxInterval.Seconds() {
+		// the integral would jump by the whole gap
+		s.pid.Reset()
+		return steering
+	}
+
+	// turning right is a clockwise, so negative, yaw rate
+	target := steering * s.Config.MaxYawRate
+	correction := s.pid.Update(target-(-yawRate), dt)
+
+	return clamp(steering+correction, -1, 1)
+}
+
+// Reset clears the loop state. It is called whenever the stabilizer is not
+// being used, so that the integral does not carry over.
+func (s *Stabilizer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pid.Reset()
+	s.last = time.Time{}
+}
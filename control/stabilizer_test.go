@@ -0,0 +1,78 @@
+package control
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStabilizer(t *testing.T) {
+	c := StabilizerConfig{MaxYawRate: 180, Kp: 0.004}
+	tests := []struct {
+		name              string
+		steering, yawRate float64
+		want              float64
+	}{
+		{"straight", 0, 0, 0},
+		{"turning as asked", 0.5, -90, 0.5},
+		{"turning too little", 0.5, -45, 0.68},
+		{"not turning", 0.5, 0, 0.86},
+		{"turning too much", 0.5, -180, 0.14},
+		{"turning the wrong way", -0.5, -90, -1},
+		{"clamped", 1, 0, 1},
+	}
+	for _, tt := range tests {
+		s := NewStabilizer(c)
+		if got := s.Update(tt.steering, tt.yawRate); !near(got, tt.want, 1e-9) {
+			t.Errorf("%s: got %.3f, want %.3f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStabilizerEnabled(t *testing.T) {
+	c := DefaultStabilizerConfig()
+	c.Modes = []Mode{Local}
+	if !c.Enabled(Local) {
+		t.Error("not enabled in the local mode")
+	}
+	if c.Enabled(User) {
+		t.Error("enabled in the user mode")
+	}
+}
+
+func TestStabilizerGap(t *testing.T) {
+	c := StabilizerConfig{MaxYawRate: 180, Kp: 0.004, Ki: 0.002}
+	start := time.Date(2018, 8, 28, 10, 0, 0, 0, time.UTC)
+	at := func(s float64) time.Time { return start.Add(seconds(s)) }
+
+	tests := []struct {
+		name  string
+		reset bool
+	}{
+		// the car calls Reset when the mode changes to one without the
+		// stabilizer, or the car stops
+		{"mode toggled", true},
+		// and the gap alone is enough if it does not
+		{"long gap", false},
+	}
+	for _, tt := range tests {
+		s := NewStabilizer(c)
+		s.MaxInterval = 200 * time.Millisecond
+		for i := 0; i < 10; i++ {
+			s.update(0.5, 0, at(float64(i)*0.05))
+		}
+		if tt.reset {
+			s.Reset()
+		}
+
+		// ten seconds later the car is still not turning; carried over, the
+		// gap would wind the integral up to full lock
+		first := s.update(0.5, 0, at(10))
+		if first > 0.5+0.004*90 {
+			t.Errorf("%s: first correction after the gap gives %.3f", tt.name, first)
+		}
+		next := s.update(0.5, 0, at(10.05))
+		if want := 0.5 + 0.004*90 + 0.002*90*0.05; !near(next, want, 1e-9) {
+			t.Errorf("%s: next correction gives %.3f, want %.3f", tt.name, next, want)
+		}
+	}
+}
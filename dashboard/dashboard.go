@@ -0,0 +1,200 @@
+// Package dashboard draws pages of car telemetry for the small SSD1306 OLED
+// display.
+//
+// The pages are drawn into a gg.Context, so they can be rendered to a PNG on
+// a PC to check the layout without the car:
+//
+//	ctx := gg.NewContext(dashboard.Width, dashboard.Height)
+//	dashboard.New().Render(ctx, data)
+//	ctx.SavePNG("page.png")
+package dashboard
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fogleman/gg"
+
+	"github.com/hybridgroup/gophercar/telemetry"
+)
+
+const (
+	// Width and Height are the size of the SSD1306 display in pixels.
+	Width  = 128
+	Height = 64
+
+	lineHeight = 13
+)
+
+// Page is a single screen of the dashboard.
+type Page struct {
+	Title string
+	Draw  func(ctx *gg.Context, d telemetry.Data)
+}
+
+// Dashboard is a set of pages, one of which is shown at a time.
+type Dashboard struct {
+	Pages []Page
+
+	mu      sync.Mutex
+	current int
+}
+
+// New returns a Dashboard with the default pages.
+func New() *Dashboard {
+	return &Dashboard{
+		Pages: []Page{
+			{Title: "Drive", Draw: drawDrive},
+			{Title: "Controls", Draw: drawControls},
+			{Title: "Network", Draw: drawNetwork},
+			{Title: "IMU", Draw: drawIMU},
+			{Title: "Recording", Draw: drawRecording},
+			{Title: "Vision", Draw: drawVision},
+			{Title: "System", Draw: drawSystem},
+		},
+	}
+}
+
+// Next moves on to the next page, wrapping around after the last one.
+func (b *Dashboard) Next() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.current = (b.current + 1) % len(b.Pages)
+}
+
+// Current returns the index of the page being shown.
+func (b *Dashboard) Current() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current
+}
+
+// Render draws the current page, with a title bar, for the given telemetry.
+func (b *Dashboard) Render(ctx *gg.Context, d telemetry.Data) {
+	b.RenderPage(ctx, b.Current(), d)
+}
+
+// RenderPage draws the page at index i, with a title bar, for the given telemetry.
+func (b *Dashboard) RenderPage(ctx *gg.Context, i int, d telemetry.Data) {
+	page := b.Pages[i]
+
+	ctx.SetRGB(0, 0, 0)
+	ctx.Clear()
+	ctx.SetRGB(1, 1, 1)
+
+	ctx.DrawStringAnchored(page.Title, 0, 0, 0, 1)
+	ctx.DrawStringAnchored(fmt.Sprintf("%d/%d", i+1, len(b.Pages)), Width, 0, 1, 1)
+	ctx.SetLineWidth(1)
+	ctx.DrawLine(0, lineHeight+1, Width, lineHeight+1)
+	ctx.Stroke()
+
+	page.Draw(ctx, d)
+}
+
+// line draws text on one of the lines below the title bar, starting from 0.
+func line(ctx *gg.Context, n int, format string, a ...interface{}) {
+	ctx.DrawStringAnchored(fmt.Sprintf(format, a...), 0, float64(lineHeight*(n+1)+3), 0, 1)
+}
+
+// bar draws a horizontal bar for a value from -1.0 to 1.0, filled out from the
+// centre, on one of the lines below the title bar.
+func bar(ctx *gg.Context, n int, label string, val float64) {
+	const labelWidth = 16
+	y := float64(lineHeight*(n+1) + 5)
+	w := float64(Width - labelWidth - 1)
+	h := float64(lineHeight - 4)
+
+	ctx.DrawStringAnchored(label, 0, y-2, 0, 1)
+
+	ctx.DrawRectangle(labelWidth, y, w, h)
+	ctx.Stroke()
+
+	if val > 1 {
+		val = 1
+	}
+	if val < -1 {
+		val = -1
+	}
+	mid := labelWidth + w/2
+	ctx.DrawRectangle(mid, y, val*w/2, h)
+	ctx.Fill()
+	ctx.DrawLine(mid, y-1, mid, y+h+1)
+	ctx.Stroke()
+}
+
+func drawDrive(ctx *gg.Context, d telemetry.Data) {
+	mode := d.Mode
+	if mode == "" {
+		mode = "-"
+	}
+	line(ctx, 0, "Mode: %s", mode)
+	if d.Crash != nil {
+		line(ctx, 1, "CRASH: %s", d.Crash.Kind)
+		line(ctx, 2, "Reset to drive")
+		return
+	}
+	if len(d.FailedDevices) > 0 {
+		line(ctx, 1, "I2C: %s", strings.Join(d.FailedDevices, ","))
+		line(ctx, 2, "Reset to drive")
+		return
+	}
+	line(ctx, 1, "%s", time.Now().Format("15:04:05"))
+	if d.Odometer > 0 {
+		line(ctx, 2, "%.2f m/s %.0f m", d.Speed, d.Odometer)
+	}
+}
+
+func drawControls(ctx *gg.Context, d telemetry.Data) {
+	bar(ctx, 0, "S", d.Steering)
+	bar(ctx, 1, "T", d.Throttle)
+	line(ctx, 2, "S %+.2f T %+.2f", d.Steering, d.Throttle)
+}
+
+func drawNetwork(ctx *gg.Context, d telemetry.Data) {
+	ip := telemetry.LocalIP()
+	if ip == "" {
+		ip = "no network"
+	}
+	line(ctx, 0, "IP: %s", ip)
+
+	if d.Address == "" {
+		line(ctx, 1, "No server")
+		return
+	}
+	_, port, err := net.SplitHostPort(d.Address)
+	if err != nil {
+		port = d.Address
+	}
+	line(ctx, 1, "Port: %s", port)
+}
+
+func drawIMU(ctx *gg.Context, d telemetry.Data) {
+	line(ctx, 0, "Roll  %6.1f", d.Attitude.Roll)
+	line(ctx, 1, "Pitch %6.1f", d.Attitude.Pitch)
+	line(ctx, 2, "Yaw %5.0f %5.0f/s", d.Attitude.Yaw, d.Attitude.YawRate)
+}
+
+func drawRecording(ctx *gg.Context, d telemetry.Data) {
+	if d.Recording {
+		line(ctx, 0, "Recording: ON")
+	} else {
+		line(ctx, 0, "Recording: off")
+	}
+	line(ctx, 1, "Records: %d", d.Records)
+}
+
+func drawVision(ctx *gg.Context, d telemetry.Data) {
+	if d.LineFound {
+		line(ctx, 0, "Line: found")
+	} else {
+		line(ctx, 0, "Line: LOST")
+	}
+}
+
+func drawSystem(ctx *gg.Context, d telemetry.Data) {
+	line(ctx, 0, "CPU: %.1f C", d.CPUTemperature)
+	line(ctx, 1, "%s", time.Now().Format("15:04:05"))
+}
@@ -0,0 +1,96 @@
+package dashboard
+
+import (
+	"image"
+	"testing"
+
+	"github.com/fogleman/gg"
+
+	"github.com/hybridgroup/gophercar/imu"
+	"github.com/hybridgroup/gophercar/telemetry"
+)
+
+// lit counts the pixels that are on in rows y0 to y1 of img.
+func lit(img image.Image, y0, y1 int) int {
+	n := 0
+	for y := y0; y < y1; y++ {
+		for x := 0; x < Width; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r > 0x8000 {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func render(b *Dashboard, i int, d telemetry.Data) image.Image {
+	ctx := gg.NewContext(Width, Height)
+	b.RenderPage(ctx, i, d)
+	return ctx.Image()
+}
+
+func TestRenderPages(t *testing.T) {
+	d := telemetry.Data{
+		Mode:      "local",
+		Steering:  -0.4,
+		Throttle:  0.3,
+		Speed:     1.2,
+		Odometer:  35,
+		Address:   ":8080",
+		Attitude:  imu.Attitude{Roll: 2, Pitch: -1, Yaw: 90, YawRate: 12},
+		Recording: true,
+		Records:   120,
+		LineFound: true,
+	}
+	b := New()
+	for i, page := range b.Pages {
+		img := render(b, i, d)
+		if img.Bounds() != image.Rect(0, 0, Width, Height) {
+			t.Errorf("%s: image is %v", page.Title, img.Bounds())
+		}
+		if lit(img, 0, lineHeight) == 0 {
+			t.Errorf("%s: no title bar", page.Title)
+		}
+		if lit(img, lineHeight+3, Height) == 0 {
+			t.Errorf("%s: nothing drawn below the title bar", page.Title)
+		}
+	}
+}
+
+func TestRenderDriveStopped(t *testing.T) {
+	running := telemetry.Data{Mode: "local"}
+	tests := []struct {
+		name string
+		d    telemetry.Data
+	}{
+		{"crash", telemetry.Data{Mode: "local", Crash: &imu.Event{Kind: imu.Rollover}}},
+		{"failed device", telemetry.Data{Mode: "local", FailedDevices: []string{"pca9685", "mpu6050"}}},
+	}
+
+	// without an odometer the running page leaves the third line empty,
+	// where the stopped pages say to reset
+	b := New()
+	normal := lit(render(b, 0, running), 3*lineHeight+3, Height)
+	for _, tt := range tests {
+		img := render(b, 0, tt.d)
+		if n := lit(img, 3*lineHeight+3, Height); n == 0 || n == normal {
+			t.Errorf("%s: no reset message on the drive page", tt.name)
+		}
+	}
+	crash := render(b, 0, tests[0].d)
+	failed := render(b, 0, tests[1].d)
+	if lit(crash, 2*lineHeight+3, 3*lineHeight+3) == lit(failed, 2*lineHeight+3, 3*lineHeight+3) {
+		t.Error("a crash and a failed device look the same")
+	}
+}
+
+func TestNext(t *testing.T) {
+	b := New()
+	n := len(b.Pages)
+	for i := 0; i < 2*n+1; i++ {
+		if got := b.Current(); got != i%n {
+			t.Fatalf("after %d pages showing %d, want %d", i, got, i%n)
+		}
+		b.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package deploy
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultPackage is the package built for the car.
+const DefaultPackage = "github.com/hybridgroup/gophercar/cmd/gophercar"
+
+// Build cross-compiles a package for the Pi. gocv needs cgo, so a C and C++
+// cross compiler for linux/arm are needed, along with a sysroot holding the
+// OpenCV headers and libraries from Raspbian.
+type Build struct {
+	Package string
+	Output  string
+
+	GOARM   string
+	CC      string
+	CXX     string
+	Sysroot string
+}
+
+// Env returns the environment for the go tool.
+func (b Build) Env() []string {
+	env := append(os.Environ(),
+		"GOOS=linux",
+		"GOARCH=arm",
+		"GOARM="+b.GOARM,
+		"CGO_ENABLED=1",
+		"CC="+b.CC,
+		"CXX="+b.CXX,
+	)
+	if b.Sysroot != "" {
+		flags := "--sysroot=" + b.Sysroot
+		env = append(env,
+			"CGO_CFLAGS="+flags,
+			"CGO_CXXFLAGS="+flags,
+			"CGO_LDFLAGS="+flags,
+			"PKG_CONFIG_SYSROOT_DIR="+b.Sysroot,
+			"PKG_CONFIG_LIBDIR="+strings.Join([]string{
+				filepath.Join(b.Sysroot, "usr/lib/arm-linux-gnueabihf/pkgconfig"),
+				filepath.Join(b.Sysroot, "usr/lib/pkgconfig"),
+				filepath.Join(b.Sysroot, "usr/share/pkgconfig"),
+				filepath.Join(b.Sysroot, "usr/local/lib/pkgconfig"),
+			}, string(os.PathListSeparator)),
+		)
+	}
+	return env
+}
+
+// Run runs go build, writing its output to w.
+func (b Build) Run(w io.Writer) error {
+	pkg := b.Package
+	if pkg == "" {
+		pkg = DefaultPackage
+	}
+	if err := os.MkdirAll(filepath.Dir(b.Output), 0755); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("go", "build", "-o", b.Output, pkg)
+	cmd.Env = b.Env()
+	cmd.Stdout = w
+	cmd.Stderr = w
+	return cmd.Run()
+}
@@ -0,0 +1,105 @@
+// Package deploy installs a car on its Raspberry Pi: it cross-compiles the
+// gophercar command, copies it and its config over SFTP, skipping files that
+// have not changed, and runs it as a systemd service.
+package deploy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+)
+
+// ManifestName is the file in the install directory that lists the checksum
+// of each file copied to it.
+const ManifestName = ".deploy.json"
+
+// Remote is the computer a car is deployed to. SSH is the Remote for a real
+// Pi.
+type Remote interface {
+	ReadFile(path string) ([]byte, error)
+	Stat(path string) (os.FileInfo, error)
+
+	// Upload writes the contents of r to path, creating its directory. The
+	// file is replaced in one step, so that a running program can be updated.
+	Upload(path string, r io.Reader, mode os.FileMode) error
+
+	// Run runs a shell command and returns its combined output.
+	Run(cmd string) ([]byte, error)
+
+	Close() error
+}
+
+// File is a file to copy to the install directory. Its contents are read
+// from Local, or taken from Data if Local is empty.
+type File struct {
+	Name  string
+	Local string
+	Data  []byte
+	Mode  os.FileMode
+}
+
+func (f File) read() ([]byte, error) {
+	if f.Local == "" {
+		return f.Data, nil
+	}
+	return ioutil.ReadFile(f.Local)
+}
+
+// manifestEntry is the checksum and size of a copied file.
+type manifestEntry struct {
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Sync copies each file to dir on the remote, skipping the ones whose
+// checksum matches the manifest of the last deploy and whose size has not
+// changed since. It returns the number of files copied.
+func Sync(r Remote, dir string, files []File, log io.Writer) (int, error) {
+	manifest := make(map[string]manifestEntry)
+	if data, err := r.ReadFile(path.Join(dir, ManifestName)); err == nil {
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			fmt.Fprintf(log, "ignoring the manifest on the car: %v\n", err)
+			manifest = make(map[string]manifestEntry)
+		}
+	}
+
+	copied := 0
+	for _, f := range files {
+		data, err := f.read()
+		if err != nil {
+			return copied, err
+		}
+		sum := sha256.Sum256(data)
+		entry := manifestEntry{SHA256: hex.EncodeToString(sum[:]), Size: int64(len(data))}
+		remote := path.Join(dir, f.Name)
+
+		if old, ok := manifest[f.Name]; ok && old == entry {
+			if fi, err := r.Stat(remote); err == nil && fi.Size() == entry.Size {
+				fmt.Fprintf(log, "%-20s unchanged\n", f.Name)
+				continue
+			}
+		}
+
+		if err := r.Upload(remote, bytes.NewReader(data), f.Mode); err != nil {
+			return copied, fmt.Errorf("copying %s: %v", f.Name, err)
+		}
+		fmt.Fprintf(log, "%-20s copied %d bytes\n", f.Name, len(data))
+		manifest[f.Name] = entry
+		copied++
+	}
+
+	if copied == 0 {
+		return 0, nil
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return copied, err
+	}
+	return copied, r.Upload(path.Join(dir, ManifestName), bytes.NewReader(data), 0644)
+}
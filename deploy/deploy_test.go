@@ -0,0 +1,255 @@
+package deploy
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/hybridgroup/gophercar/deploy/sshtest"
+)
+
+// dial starts an sshtest server and connects to it, with a temporary
+// directory to install into.
+func dial(t *testing.T) (*sshtest.Server, *SSH, string) {
+	dir, err := ioutil.TempDir("", "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := sshtest.NewServer(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	r, err := Dial(server.Addr, "pi", []ssh.AuthMethod{server.ClientAuth()}, ssh.FixedHostKey(server.HostKey()))
+	if err != nil {
+		server.Close()
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return server, r, server.Root
+}
+
+func cleanup(server *sshtest.Server, r *SSH, dir string) {
+	r.Close()
+	server.Close()
+	os.RemoveAll(dir)
+}
+
+func TestSync(t *testing.T) {
+	server, r, dir := dial(t)
+	defer cleanup(server, r, dir)
+
+	files := []File{
+		{Name: "gophercar", Data: []byte("binary"), Mode: 0755},
+		{Name: "gophercar.json", Data: []byte(`{"car":{}}`), Mode: 0644},
+	}
+	tests := []struct {
+		name      string
+		change    func()
+		copied    int
+		unchanged []string
+	}{
+		{"first deploy", func() {}, 2, nil},
+		{"nothing changed", func() {}, 0, []string{"gophercar", "gophercar.json"}},
+		{"config changed", func() { files[1].Data = []byte(`{"car":{"name":"blue"}}`) }, 1, []string{"gophercar"}},
+		{"file removed on the car", func() { os.Remove(filepath.Join(dir, "gophercar")) }, 1, []string{"gophercar.json"}},
+	}
+	for _, tt := range tests {
+		tt.change()
+		var log bytes.Buffer
+		copied, err := Sync(r, dir, files, &log)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if copied != tt.copied {
+			t.Errorf("%s: copied %d files, want %d\n%s", tt.name, copied, tt.copied, log.String())
+		}
+		for _, name := range tt.unchanged {
+			if !strings.Contains(log.String(), fmt.Sprintf("%-20s unchanged", name)) {
+				t.Errorf("%s: %s not skipped\n%s", tt.name, name, log.String())
+			}
+		}
+
+		for _, f := range files {
+			data, err := ioutil.ReadFile(filepath.Join(dir, f.Name))
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if !bytes.Equal(data, f.Data) {
+				t.Errorf("%s: %s is %q, want %q", tt.name, f.Name, data, f.Data)
+			}
+		}
+	}
+
+	fi, err := os.Stat(filepath.Join(dir, "gophercar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0755 {
+		t.Errorf("gophercar mode is %v, want 0755", fi.Mode().Perm())
+	}
+}
+
+func TestSyncManifest(t *testing.T) {
+	server, r, dir := dial(t)
+	defer cleanup(server, r, dir)
+
+	readManifest := func() map[string]manifestEntry {
+		data, err := ioutil.ReadFile(filepath.Join(dir, ManifestName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := make(map[string]manifestEntry)
+		if err := json.Unmarshal(data, &m); err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	entry := func(data string) manifestEntry {
+		sum := sha256.Sum256([]byte(data))
+		return manifestEntry{SHA256: hex.EncodeToString(sum[:]), Size: int64(len(data))}
+	}
+
+	files := []File{
+		{Name: "a", Data: []byte("first"), Mode: 0644},
+		{Name: "b", Data: []byte("second"), Mode: 0644},
+	}
+	if _, err := Sync(r, dir, files, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	m := readManifest()
+	if m["a"] != entry("first") || m["b"] != entry("second") || len(m) != 2 {
+		t.Errorf("after the first deploy the manifest is %v", m)
+	}
+
+	// the manifest is rewritten with the new checksum, and keeps the entry
+	// of the file that was skipped
+	files[0].Data = []byte("changed")
+	if _, err := Sync(r, dir, files, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+	m = readManifest()
+	if m["a"] != entry("changed") || m["b"] != entry("second") || len(m) != 2 {
+		t.Errorf("after a change the manifest is %v", m)
+	}
+
+	// a broken manifest is ignored, so everything is copied again
+	if err := ioutil.WriteFile(filepath.Join(dir, ManifestName), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	copied, err := Sync(r, dir, files, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied != 2 {
+		t.Errorf("with a broken manifest copied %d files, want 2", copied)
+	}
+	m = readManifest()
+	if m["a"] != entry("changed") || m["b"] != entry("second") || len(m) != 2 {
+		t.Errorf("after a broken manifest the manifest is %v", m)
+	}
+}
+
+func TestUnit(t *testing.T) {
+	server, r, dir := dial(t)
+	defer cleanup(server, r, dir)
+
+	server.Exec = func(cmd string) (string, int) {
+		if strings.Contains(cmd, "status") {
+			return "active (running)", 0
+		}
+		if strings.Contains(cmd, "stop") {
+			return "Failed to stop", 1
+		}
+		return "", 0
+	}
+	u := Unit{Name: "gophercar", User: "pi", Dir: dir, Run: "autopilot"}
+
+	if err := u.Install(r); err != nil {
+		t.Fatal(err)
+	}
+	out, err := u.Control(r, "status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "active (running)" {
+		t.Errorf("status output is %q", out)
+	}
+	if _, err := u.Control(r, "restart"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Control(r, "stop"); err == nil || !strings.Contains(err.Error(), "Failed to stop") {
+		t.Errorf("a failed stop returned %v", err)
+	}
+	if _, err := u.Control(r, "reboot"); err == nil {
+		t.Error("an unknown action was accepted")
+	}
+
+	want := []string{
+		"sudo install -m 0644 '" + dir + "/gophercar.service' '/etc/systemd/system/gophercar.service' && sudo systemctl daemon-reload && sudo systemctl enable 'gophercar'",
+		"systemctl status --no-pager 'gophercar'; true",
+		"sudo systemctl restart 'gophercar'",
+		"sudo systemctl stop 'gophercar'",
+	}
+	got := server.Commands()
+	if len(got) != len(want) {
+		t.Fatalf("ran %d commands, want %d: %q", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("command %d is %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStandIn(t *testing.T) {
+	server, r, dir := dial(t)
+	defer cleanup(server, r, dir)
+
+	outside, err := ioutil.TempDir("", "outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+	if err := ioutil.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{
+		filepath.Join(outside, "secret"),
+		dir + "/../" + filepath.Base(outside) + "/secret",
+		filepath.Join(dir, "link", "secret"),
+	} {
+		if _, err := r.ReadFile(name); err == nil {
+			t.Errorf("read %s outside the stand-in directory", name)
+		}
+		if err := r.Upload(name, strings.NewReader("changed"), 0644); err == nil {
+			t.Errorf("wrote %s outside the stand-in directory", name)
+		}
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(outside, "secret")); string(data) != "secret" {
+		t.Errorf("the file outside was changed to %q", data)
+	}
+
+	other, err := ssh.NewSignerFromKey(ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := Dial(server.Addr, "pi", []ssh.AuthMethod{ssh.PublicKeys(other)}, ssh.FixedHostKey(server.HostKey())); err == nil {
+		r.Close()
+		t.Error("logged in with a key the stand-in does not know")
+	}
+}
@@ -0,0 +1,92 @@
+package deploy
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// Actions are the systemctl actions for the service of a car.
+var Actions = []string{"start", "stop", "restart", "status"}
+
+// Unit is the systemd service that runs the car.
+type Unit struct {
+	Name string
+	User string
+	Dir  string
+
+	// Run is the gophercar command and flags to run, such as "autopilot".
+	Run string
+}
+
+var unitTemplate = template.Must(template.New("unit").Parse(`[Unit]
+Description=Gophercar {{.Run}}
+After=network.target
+
+[Service]
+User={{.User}}
+WorkingDirectory={{.Dir}}
+ExecStart={{.Dir}}/gophercar -config {{.Dir}}/gophercar.json {{.Run}}
+Restart=on-failure
+RestartSec=2
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+// File returns the unit file to copy to the install directory.
+func (u Unit) File() (File, error) {
+	var buf bytes.Buffer
+	if err := unitTemplate.Execute(&buf, u); err != nil {
+		return File{}, err
+	}
+	return File{Name: u.Name + ".service", Data: buf.Bytes(), Mode: 0644}, nil
+}
+
+// Install copies the unit file, already copied to the install directory, to
+// systemd and enables it, so the car starts when the Pi boots.
+func (u Unit) Install(r Remote) error {
+	unit := u.Name + ".service"
+	_, err := run(r, fmt.Sprintf("sudo install -m 0644 %s %s && sudo systemctl daemon-reload && sudo systemctl enable %s",
+		quote(path.Join(u.Dir, unit)), quote(path.Join("/etc/systemd/system", unit)), quote(u.Name)))
+	return err
+}
+
+// Control runs a systemctl action on the service, and returns its output.
+func (u Unit) Control(r Remote, action string) ([]byte, error) {
+	ok := false
+	for _, a := range Actions {
+		ok = ok || a == action
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown action %q, use one of %s", action, strings.Join(Actions, ", "))
+	}
+
+	cmd := fmt.Sprintf("sudo systemctl %s %s", action, quote(u.Name))
+	if action == "status" {
+		// status fails when the service is stopped, which is not an error here
+		cmd = fmt.Sprintf("systemctl status --no-pager %s; true", quote(u.Name))
+	}
+	return run(r, cmd)
+}
+
+// Logs returns the last n lines of the log of the service.
+func (u Unit) Logs(r Remote, n int) ([]byte, error) {
+	return run(r, fmt.Sprintf("journalctl --no-pager -n %d -u %s", n, quote(u.Name)))
+}
+
+// run runs cmd, adding its output to the error if it fails.
+func run(r Remote, cmd string) ([]byte, error) {
+	out, err := r.Run(cmd)
+	if err != nil {
+		return out, fmt.Errorf("%s: %v\n%s", cmd, err, out)
+	}
+	return out, nil
+}
+
+// quote quotes s for the shell.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,136 @@
+package deploy
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSH is a Remote reached over SSH, with files copied by SFTP.
+type SSH struct {
+	client *ssh.Client
+	sftp   *sftp.Client
+}
+
+// Dial connects to addr, which is a host or host:port, as user, logging in
+// with auth. Auth returns the usual keys.
+func Dial(addr, user string, auth []ssh.AuthMethod, hostKey ssh.HostKeyCallback) (*SSH, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	client, err := ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: hostKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &SSH{client: client, sftp: sc}, nil
+}
+
+// Auth returns the SSH agent, if there is one, and the default private keys
+// in ~/.ssh that do not need a passphrase.
+func Auth() []ssh.AuthMethod {
+	var methods []ssh.AuthMethod
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	var signers []ssh.Signer
+	home, _ := os.UserHomeDir()
+	for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+		data, err := ioutil.ReadFile(filepath.Join(home, ".ssh", name))
+		if err != nil {
+			continue
+		}
+		if signer, err := ssh.ParsePrivateKey(data); err == nil {
+			signers = append(signers, signer)
+		}
+	}
+	if len(signers) > 0 {
+		methods = append(methods, ssh.PublicKeys(signers...))
+	}
+	return methods
+}
+
+// KnownHosts checks host keys against ~/.ssh/known_hosts.
+func KnownHosts() (ssh.HostKeyCallback, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return knownhosts.New(filepath.Join(home, ".ssh", "known_hosts"))
+}
+
+// ReadFile reads a file from the remote.
+func (s *SSH) ReadFile(name string) ([]byte, error) {
+	f, err := s.sftp.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// Stat returns the FileInfo of a file on the remote.
+func (s *SSH) Stat(name string) (os.FileInfo, error) {
+	return s.sftp.Stat(name)
+}
+
+// Upload writes a temporary file next to name and renames it over name.
+func (s *SSH) Upload(name string, r io.Reader, mode os.FileMode) error {
+	if err := s.sftp.MkdirAll(path.Dir(name)); err != nil {
+		return err
+	}
+
+	tmp := name + ".tmp"
+	f, err := s.sftp.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := s.sftp.Chmod(tmp, mode); err != nil {
+		return err
+	}
+	return s.sftp.PosixRename(tmp, name)
+}
+
+// Run runs a command in a new SSH session.
+func (s *SSH) Run(cmd string) ([]byte, error) {
+	session, err := s.client.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	return session.CombinedOutput(cmd)
+}
+
+// Close closes the SFTP and SSH connections.
+func (s *SSH) Close() error {
+	s.sftp.Close()
+	return s.client.Close()
+}
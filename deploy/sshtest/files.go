@@ -0,0 +1,146 @@
+package sshtest
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/sftp"
+)
+
+// files serves SFTP requests from the local file system, refusing anything
+// outside of root.
+type files struct {
+	root string
+}
+
+// path returns the local path for an SFTP path, if it is inside root.
+func (f files) path(p string) (string, error) {
+	p = filepath.Clean(filepath.FromSlash(p))
+	if p == f.root {
+		return p, nil
+	}
+	if !within(p, f.root) {
+		return "", os.ErrPermission
+	}
+
+	// a symlink under root could still lead out of it
+	dir, err := filepath.EvalSymlinks(filepath.Dir(p))
+	if err != nil {
+		return "", err
+	}
+	if dir != f.root && !within(dir, f.root) {
+		return "", os.ErrPermission
+	}
+	if fi, err := os.Lstat(p); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		return "", os.ErrPermission
+	}
+	return p, nil
+}
+
+func within(p, dir string) bool {
+	return strings.HasPrefix(p, dir+string(filepath.Separator))
+}
+
+func (f files) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	p, err := f.path(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+func (f files) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	p, err := f.path(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+	pflags := r.Pflags()
+	flags := os.O_WRONLY
+	if pflags.Append {
+		flags |= os.O_APPEND
+	}
+	if pflags.Creat {
+		flags |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flags |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flags |= os.O_EXCL
+	}
+	return os.OpenFile(p, flags, 0644)
+}
+
+func (f files) Filecmd(r *sftp.Request) error {
+	p, err := f.path(r.Filepath)
+	if err != nil {
+		return err
+	}
+
+	switch r.Method {
+	case "Setstat":
+		attrs := r.Attributes()
+		if r.AttrFlags().Permissions {
+			if err := os.Chmod(p, attrs.FileMode().Perm()); err != nil {
+				return err
+			}
+		}
+		if r.AttrFlags().Size {
+			if err := os.Truncate(p, int64(attrs.Size)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "Rename", "PosixRename":
+		target, err := f.path(r.Target)
+		if err != nil {
+			return err
+		}
+		return os.Rename(p, target)
+	case "Mkdir":
+		return os.Mkdir(p, 0755)
+	case "Rmdir", "Remove":
+		return os.Remove(p)
+	}
+	// links could point out of root
+	return os.ErrPermission
+}
+
+func (f files) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	p, err := f.path(r.Filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r.Method {
+	case "List":
+		infos, err := ioutil.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt(infos), nil
+	case "Stat":
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{fi}, nil
+	}
+	return nil, os.ErrPermission
+}
+
+type listerAt []os.FileInfo
+
+func (l listerAt) ListAt(infos []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(infos, l[offset:])
+	if n < len(infos) {
+		return n, io.EOF
+	}
+	return n, nil
+}
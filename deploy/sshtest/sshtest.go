@@ -0,0 +1,208 @@
+// Package sshtest runs a local SSH server that stands in for the Pi of a car,
+// so that deploying can be tried out without one. Files are copied by SFTP to
+// a directory of the local file system, and commands are recorded instead of
+// run.
+package sshtest
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Server is a stand-in SSH server. It only lets in clients that log in with
+// its ClientAuth, as any local user can connect to it, and only gives them
+// the files under Root.
+type Server struct {
+	// Addr is the host:port the server listens on.
+	Addr string
+
+	// Root is the directory that files can be copied to and read from,
+	// as an absolute path with no symlinks.
+	Root string
+
+	// Exec is called with each command instead of running it, and returns
+	// its output and exit status. By default commands succeed with no output.
+	Exec func(cmd string) (output string, status int)
+
+	hostKey   ssh.Signer
+	clientKey ssh.Signer
+	listener  net.Listener
+	config    *ssh.ServerConfig
+
+	mu       sync.Mutex
+	commands []string
+}
+
+// NewServer starts a Server on a free port of the loopback interface, that
+// serves the files under root, creating it if needed.
+func NewServer(root string) (*Server, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, err
+	}
+
+	hostKey, err := newSigner()
+	if err != nil {
+		return nil, err
+	}
+	clientKey, err := newSigner()
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		Addr:      l.Addr().String(),
+		Root:      root,
+		hostKey:   hostKey,
+		clientKey: clientKey,
+		listener:  l,
+	}
+	allowed := clientKey.PublicKey().Marshal()
+	s.config = &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if !bytes.Equal(key.Marshal(), allowed) {
+				return nil, errors.New("sshtest: unknown key")
+			}
+			return nil, nil
+		},
+	}
+	s.config.AddHostKey(hostKey)
+
+	go s.serve()
+	return s, nil
+}
+
+func newSigner() (ssh.Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(key)
+}
+
+// ClientAuth returns the only key the server lets in.
+func (s *Server) ClientAuth() ssh.AuthMethod {
+	return ssh.PublicKeys(s.clientKey)
+}
+
+// HostKey returns the public host key of the server, to check it with
+// ssh.FixedHostKey.
+func (s *Server) HostKey() ssh.PublicKey {
+	return s.hostKey.PublicKey()
+}
+
+// Commands returns the commands run so far.
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			continue
+		}
+		go s.handleSession(ch, requests)
+	}
+}
+
+func (s *Server) handleSession(ch ssh.Channel, requests <-chan *ssh.Request) {
+	defer ch.Close()
+
+	for req := range requests {
+		switch req.Type {
+		case "subsystem":
+			if payloadString(req.Payload) != "sftp" {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			fs := files{root: s.Root}
+			server := sftp.NewRequestServer(ch, sftp.Handlers{FileGet: fs, FilePut: fs, FileCmd: fs, FileList: fs})
+			server.Serve()
+			server.Close()
+			return
+		case "exec":
+			req.Reply(true, nil)
+			status := s.exec(ch, payloadString(req.Payload))
+			ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+			return
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+func (s *Server) exec(ch ssh.Channel, cmd string) int {
+	s.mu.Lock()
+	s.commands = append(s.commands, cmd)
+	s.mu.Unlock()
+
+	if s.Exec == nil {
+		return 0
+	}
+	out, status := s.Exec(cmd)
+	ch.Write([]byte(out))
+	return status
+}
+
+// payloadString reads the string at the start of an SSH request payload.
+func payloadString(payload []byte) string {
+	if len(payload) < 4 {
+		return ""
+	}
+	n := binary.BigEndian.Uint32(payload)
+	if int(n) > len(payload)-4 {
+		return ""
+	}
+	return string(payload[4 : 4+n])
+}
@@ -0,0 +1,216 @@
+// Package detect finds objects such as stop signs, people and other cars in
+// the camera frames, with a neural network or a Haar cascade, so that the car
+// can react to them.
+package detect
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/control"
+)
+
+// Detection is an object found in a frame.
+type Detection struct {
+	Class      string
+	Confidence float64
+	Box        image.Rectangle
+}
+
+// Detector finds objects in frames.
+type Detector interface {
+	// Detect returns the objects in a BGR frame. It must not modify or keep
+	// the frame.
+	Detect(img gocv.Mat) []Detection
+
+	Close() error
+}
+
+// SSD is a Detector that runs a Single Shot MultiBox Detector network, such
+// as MobileNet-SSD, with the OpenCV DNN module.
+type SSD struct {
+	Net gocv.Net
+
+	// Labels are the class names by class ID. Classes without a label are
+	// named by their ID.
+	Labels []string
+
+	// MinConfidence is the lowest confidence from 0 to 1 of the detections
+	// returned.
+	MinConfidence float64
+
+	// Size is the size of the network input, and Scale and Mean the scale
+	// and the mean subtracted from each pixel value before it.
+	Size  image.Point
+	Scale float64
+	Mean  gocv.Scalar
+
+	// SwapRB is set for networks trained on RGB images.
+	SwapRB bool
+}
+
+// NewSSD loads a network from a model file and, for Caffe models, its
+// config, with the input settings of the MobileNet-SSD Caffe model.
+func NewSSD(model, config string) (*SSD, error) {
+	net := gocv.ReadNet(model, config)
+	if net.Empty() {
+		return nil, fmt.Errorf("reading the network from %s", model)
+	}
+	return &SSD{
+		Net:           net,
+		MinConfidence: 0.5,
+		Size:          image.Pt(300, 300),
+		Scale:         1 / 127.5,
+		Mean:          gocv.NewScalar(127.5, 127.5, 127.5, 0),
+	}, nil
+}
+
+// Detect runs the network on a frame.
+func (d *SSD) Detect(img gocv.Mat) []Detection {
+	if img.Empty() {
+		return nil
+	}
+	blob := gocv.BlobFromImage(img, d.Scale, d.Size, d.Mean, d.SwapRB, false)
+	defer blob.Close()
+	d.Net.SetInput(blob, "")
+	out := d.Net.Forward("")
+	defer out.Close()
+
+	// the output holds 7 values for each detection: the image ID, class ID,
+	// confidence, and left, top, right and bottom as fractions of the frame
+	results := out.Reshape(1, 1)
+	defer results.Close()
+	w, h := float64(img.Cols()), float64(img.Rows())
+	var dets []Detection
+	for i := 0; i+7 <= results.Total(); i += 7 {
+		confidence := float64(results.GetFloatAt(0, i+2))
+		if confidence < d.MinConfidence {
+			continue
+		}
+		box := image.Rect(
+			int(float64(results.GetFloatAt(0, i+3))*w),
+			int(float64(results.GetFloatAt(0, i+4))*h),
+			int(float64(results.GetFloatAt(0, i+5))*w),
+			int(float64(results.GetFloatAt(0, i+6))*h),
+		)
+		dets = append(dets, Detection{
+			Class:      d.label(int(results.GetFloatAt(0, i+1))),
+			Confidence: confidence,
+			Box:        box.Intersect(image.Rect(0, 0, img.Cols(), img.Rows())),
+		})
+	}
+	return dets
+}
+
+func (d *SSD) label(id int) string {
+	if id >= 0 && id < len(d.Labels) && d.Labels[id] != "" {
+		return d.Labels[id]
+	}
+	return strconv.Itoa(id)
+}
+
+// Close frees the network.
+func (d *SSD) Close() error {
+	return d.Net.Close()
+}
+
+// Cascade is a Detector that finds one class of object with a Haar cascade,
+// such as a stop sign cascade. Its detections all have a confidence of 1.
+type Cascade struct {
+	Class      string
+	Classifier gocv.CascadeClassifier
+
+	gray gocv.Mat
+}
+
+// NewCascade loads the Haar cascade at path for finding objects of class.
+func NewCascade(path, class string) (*Cascade, error) {
+	c := gocv.NewCascadeClassifier()
+	if !c.Load(path) {
+		c.Close()
+		return nil, fmt.Errorf("reading the cascade from %s", path)
+	}
+	return &Cascade{Class: class, Classifier: c, gray: gocv.NewMat()}, nil
+}
+
+// Detect runs the cascade on a frame.
+func (d *Cascade) Detect(img gocv.Mat) []Detection {
+	if img.Empty() {
+		return nil
+	}
+	gocv.CvtColor(img, &d.gray, gocv.ColorBGRToGray)
+	var dets []Detection
+	for _, r := range d.Classifier.DetectMultiScale(d.gray) {
+		dets = append(dets, Detection{Class: d.Class, Confidence: 1, Box: r})
+	}
+	return dets
+}
+
+// Close frees the cascade.
+func (d *Cascade) Close() error {
+	d.gray.Close()
+	return d.Classifier.Close()
+}
+
+// LoadLabels reads class names from a file with one name on each line, the
+// first line being class ID 0.
+func LoadLabels(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var labels []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		labels = append(labels, strings.TrimSpace(s.Text()))
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(labels) == 0 {
+		return nil, errors.New("no labels in " + path)
+	}
+	return labels, nil
+}
+
+// Sightings returns the detections in a frame of size as the control package
+// sees them, with the fraction of the frame each one fills.
+func Sightings(dets []Detection, size image.Point) []control.Sighting {
+	frame := float64(size.X * size.Y)
+	if frame == 0 {
+		return nil
+	}
+	seen := make([]control.Sighting, len(dets))
+	for i, d := range dets {
+		b := d.Box.Size()
+		seen[i] = control.Sighting{
+			Class:      d.Class,
+			Confidence: d.Confidence,
+			Area:       float64(b.X*b.Y) / frame,
+		}
+	}
+	return seen
+}
+
+var boxColor = color.RGBA{R: 255, G: 128, A: 255}
+
+// Draw draws a box and label around each detection, moved by offset, such as
+// to draw on the cropped part of the frame that a pilot annotated.
+func Draw(img *gocv.Mat, dets []Detection, offset image.Point) {
+	for _, d := range dets {
+		box := d.Box.Add(offset)
+		gocv.Rectangle(img, box, boxColor, 2)
+		label := fmt.Sprintf("%s %.0f%%", d.Class, d.Confidence*100)
+		gocv.PutText(img, label, image.Pt(box.Min.X, box.Min.Y-4), gocv.FontHersheySimplex, 0.4, boxColor, 1)
+	}
+}
@@ -0,0 +1,179 @@
+// Package device wraps the I2C devices of a car so that their errors are not
+// lost. Each call is retried a few times, since a single read or write on the
+// I2C bus sometimes fails while the motors are noisy, and every error is
+// counted. A device that keeps failing is marked as failed so that the car
+// can stop driving until the operator resets it.
+package device
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults for a new Monitor.
+const (
+	DefaultRetries     = 2
+	DefaultRetryDelay  = time.Millisecond
+	DefaultMaxFailures = 5
+)
+
+// Health is the state of a device, as reported by the API.
+type Health struct {
+	Name string `json:"name"`
+
+	// Failed is set when the device has failed MaxFailures calls in a row,
+	// and stays set until the device is reset.
+	Failed bool `json:"failed"`
+
+	// Calls counts the calls made to the device, Errors every failed attempt,
+	// Retries the attempts that were a retry, and Failures the calls that
+	// still failed after all of their retries. ConsecutiveFailures is the
+	// number of calls in a row that have failed.
+	Calls               uint64 `json:"calls"`
+	Errors              uint64 `json:"errors"`
+	Retries             uint64 `json:"retries"`
+	Failures            uint64 `json:"failures"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorTime time.Time `json:"last_error_time,omitempty"`
+}
+
+// Monitor retries and counts the calls to a device.
+type Monitor struct {
+	Name string
+
+	// Retries is how many more times a failed call is attempted, waiting
+	// RetryDelay in between.
+	Retries    int
+	RetryDelay time.Duration
+
+	// MaxFailures is the number of calls in a row that may fail before the
+	// device is marked as failed.
+	MaxFailures int
+
+	// OnFailure is called once when the device is marked as failed.
+	OnFailure func(h Health)
+
+	mu     sync.Mutex
+	health Health
+}
+
+// NewMonitor returns a Monitor for the named device with the default retries.
+func NewMonitor(name string) *Monitor {
+	return &Monitor{
+		Name:        name,
+		Retries:     DefaultRetries,
+		RetryDelay:  DefaultRetryDelay,
+		MaxFailures: DefaultMaxFailures,
+		health:      Health{Name: name},
+	}
+}
+
+// Do calls fn, retrying it if it fails, and returns the last error.
+func (m *Monitor) Do(fn func() error) error {
+	var err error
+	for attempt := 0; attempt <= m.Retries; attempt++ {
+		if attempt > 0 && m.RetryDelay > 0 {
+			time.Sleep(m.RetryDelay)
+		}
+		if err = fn(); err == nil {
+			break
+		}
+		m.record(attempt, err)
+	}
+
+	m.mu.Lock()
+	m.health.Calls++
+	if err == nil {
+		m.health.ConsecutiveFailures = 0
+		m.mu.Unlock()
+		return nil
+	}
+
+	m.health.Failures++
+	m.health.ConsecutiveFailures++
+	failed := !m.health.Failed && m.health.ConsecutiveFailures >= m.MaxFailures
+	if failed {
+		m.health.Failed = true
+	}
+	h := m.health
+	m.mu.Unlock()
+
+	if failed && m.OnFailure != nil {
+		m.OnFailure(h)
+	}
+	return err
+}
+
+func (m *Monitor) record(attempt int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.health.Errors++
+	if attempt > 0 {
+		m.health.Retries++
+	}
+	m.health.LastError = err.Error()
+	m.health.LastErrorTime = time.Now()
+}
+
+// Health returns the current state of the device.
+func (m *Monitor) Health() Health {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.health
+}
+
+// Failed returns true if the device has been marked as failed.
+func (m *Monitor) Failed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.health.Failed
+}
+
+// Reset clears a failed device, keeping its counters.
+func (m *Monitor) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.health.Failed = false
+	m.health.ConsecutiveFailures = 0
+}
+
+// Devices is a set of monitored devices.
+type Devices []*Monitor
+
+// Health returns the state of each device.
+func (ds Devices) Health() []Health {
+	health := make([]Health, len(ds))
+	for i, m := range ds {
+		health[i] = m.Health()
+	}
+	return health
+}
+
+// Failed returns the devices that have been marked as failed.
+func (ds Devices) Failed() []string {
+	var failed []string
+	for _, m := range ds {
+		if m.Failed() {
+			failed = append(failed, m.Name)
+		}
+	}
+	return failed
+}
+
+// Reset clears all of the failed devices.
+func (ds Devices) Reset() {
+	for _, m := range ds {
+		m.Reset()
+	}
+}
+
+// ServeHTTP writes the health of each device as JSON.
+func (ds Devices) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ds.Health())
+}
@@ -0,0 +1,110 @@
+package device
+
+import (
+	"errors"
+	"testing"
+)
+
+var errBus = errors.New("i2c: remote I/O error")
+
+// failing returns a func that fails its first n calls.
+func failing(n int) (fn func() error, calls *int) {
+	calls = new(int)
+	return func() error {
+		*calls++
+		if *calls <= n {
+			return errBus
+		}
+		return nil
+	}, calls
+}
+
+func TestMonitorRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		fails    int
+		err      error
+		attempts int
+		health   Health
+	}{
+		{"works", 0, nil, 1, Health{Calls: 1}},
+		{"works on a retry", 2, nil, 3, Health{Calls: 1, Errors: 2, Retries: 1}},
+		{"fails every retry", 5, errBus, 3, Health{Calls: 1, Errors: 3, Retries: 2, Failures: 1, ConsecutiveFailures: 1}},
+	}
+	for _, tt := range tests {
+		m := NewMonitor("pca9685")
+		m.RetryDelay = 0
+		fn, calls := failing(tt.fails)
+		if err := m.Do(fn); err != tt.err {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.err)
+		}
+		if *calls != tt.attempts {
+			t.Errorf("%s: tried %d times, want %d", tt.name, *calls, tt.attempts)
+		}
+		h := m.Health()
+		counts := Health{Calls: h.Calls, Errors: h.Errors, Retries: h.Retries, Failures: h.Failures, ConsecutiveFailures: h.ConsecutiveFailures}
+		if counts != tt.health {
+			t.Errorf("%s: got health %+v, want %+v", tt.name, counts, tt.health)
+		}
+		if tt.err != nil && m.Health().LastError != errBus.Error() {
+			t.Errorf("%s: last error is %q", tt.name, m.Health().LastError)
+		}
+	}
+}
+
+func TestMonitorFails(t *testing.T) {
+	m := NewMonitor("mpu6050")
+	m.RetryDelay = 0
+	m.MaxFailures = 3
+	failures := 0
+	m.OnFailure = func(h Health) {
+		failures++
+		if h.Name != "mpu6050" || !h.Failed || h.ConsecutiveFailures != 3 {
+			t.Errorf("OnFailure got %+v", h)
+		}
+	}
+	fail := func() error { return errBus }
+	ok := func() error { return nil }
+
+	// a success in between starts the count again
+	m.Do(fail)
+	m.Do(fail)
+	m.Do(ok)
+	m.Do(fail)
+	m.Do(fail)
+	if m.Failed() {
+		t.Fatal("failed after 2 failures in a row")
+	}
+	m.Do(fail)
+	if !m.Failed() {
+		t.Fatal("not failed after 3 failures in a row")
+	}
+
+	// it stays failed, even once calls work again, and only tells once
+	m.Do(fail)
+	m.Do(ok)
+	if !m.Failed() || failures != 1 {
+		t.Errorf("failed is %v with OnFailure called %d times", m.Failed(), failures)
+	}
+
+	devices := Devices{m, NewMonitor("pca9685")}
+	if got := devices.Failed(); len(got) != 1 || got[0] != "mpu6050" {
+		t.Errorf("failed devices are %v", got)
+	}
+
+	// Reset clears it, keeps the counters, and it can fail again
+	calls := m.Health().Calls
+	devices.Reset()
+	if m.Failed() || len(devices.Failed()) != 0 || m.Health().ConsecutiveFailures != 0 {
+		t.Errorf("after Reset got %+v", m.Health())
+	}
+	if m.Health().Calls != calls {
+		t.Errorf("Reset cleared the calls")
+	}
+	for i := 0; i < 3; i++ {
+		m.Do(fail)
+	}
+	if !m.Failed() || failures != 2 {
+		t.Errorf("after failing again failed is %v with OnFailure called %d times", m.Failed(), failures)
+	}
+}
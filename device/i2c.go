@@ -0,0 +1,73 @@
+package device
+
+import (
+	"gobot.io/x/gobot/drivers/i2c"
+
+	"github.com/hybridgroup/gophercar/distance"
+	"github.com/hybridgroup/gophercar/imu"
+)
+
+// PWM is a PCA9685 PWM controller whose errors are retried and counted.
+type PWM struct {
+	*Monitor
+	driver *i2c.PCA9685Driver
+}
+
+// NewPWM returns a PWM for the PCA9685 driver.
+func NewPWM(driver *i2c.PCA9685Driver) *PWM {
+	return &PWM{Monitor: NewMonitor("pca9685"), driver: driver}
+}
+
+// SetPWMFreq sets the PWM frequency in Hz.
+func (p *PWM) SetPWMFreq(freq float32) error {
+	return p.Do(func() error { return p.driver.SetPWMFreq(freq) })
+}
+
+// SetPWM sets the on and off ticks of a channel.
+func (p *PWM) SetPWM(channel int, on, off uint16) error {
+	return p.Do(func() error { return p.driver.SetPWM(channel, on, off) })
+}
+
+// Sensor is an imu.Sensor whose errors are retried and counted.
+type Sensor struct {
+	*Monitor
+	sensor imu.Sensor
+}
+
+// NewSensor returns a Sensor for the named IMU sensor.
+func NewSensor(name string, s imu.Sensor) *Sensor {
+	return &Sensor{Monitor: NewMonitor(name), sensor: s}
+}
+
+// Read reads the sensor.
+func (s *Sensor) Read() (imu.Reading, error) {
+	var r imu.Reading
+	err := s.Do(func() error {
+		var err error
+		r, err = s.sensor.Read()
+		return err
+	})
+	return r, err
+}
+
+// Range is a distance.Sensor whose errors are retried and counted.
+type Range struct {
+	*Monitor
+	sensor distance.Sensor
+}
+
+// NewRange returns a Range for the named distance sensor.
+func NewRange(name string, s distance.Sensor) *Range {
+	return &Range{Monitor: NewMonitor(name), sensor: s}
+}
+
+// Distance reads the sensor.
+func (r *Range) Distance() (float64, error) {
+	var d float64
+	err := r.Do(func() error {
+		var err error
+		d, err = r.sensor.Distance()
+		return err
+	})
+	return d, err
+}
@@ -0,0 +1,28 @@
+package device
+
+import (
+	"io"
+
+	"github.com/hybridgroup/gophercar/metrics"
+)
+
+// WritePrometheus writes the health of each device as Prometheus metrics.
+func (ds Devices) WritePrometheus(w io.Writer) {
+	var calls, errors, retries, failed []metrics.Sample
+	for _, h := range ds.Health() {
+		labels := metrics.Label("device", h.Name)
+		calls = append(calls, metrics.Sample{Labels: labels, Value: float64(h.Calls)})
+		errors = append(errors, metrics.Sample{Labels: labels, Value: float64(h.Errors)})
+		retries = append(retries, metrics.Sample{Labels: labels, Value: float64(h.Retries)})
+		f := 0.0
+		if h.Failed {
+			f = 1
+		}
+		failed = append(failed, metrics.Sample{Labels: labels, Value: f})
+	}
+
+	metrics.WriteMetric(w, "device_calls_total", metrics.Counter, "Calls made to an I2C device.", calls...)
+	metrics.WriteMetric(w, "device_errors_total", metrics.Counter, "Failed attempts to talk to an I2C device, including retries.", errors...)
+	metrics.WriteMetric(w, "device_retries_total", metrics.Counter, "Retried attempts to talk to an I2C device.", retries...)
+	metrics.WriteMetric(w, "device_failed", metrics.Gauge, "1 if an I2C device has failed too many times in a row.", failed...)
+}
@@ -0,0 +1,17 @@
+// Package distance reads the range sensors that look ahead of the car for
+// obstacles: the HC-SR04 ultrasonic sensor on two GPIO pins, and the VL53L0X
+// time of flight sensor on I2C. Both are gobot devices, so they are started
+// and halted along with the rest of the robot.
+package distance
+
+import "errors"
+
+// ErrTimeout is returned when a sensor does not answer in time.
+var ErrTimeout = errors.New("distance sensor timed out")
+
+// Sensor measures the distance to the nearest obstacle ahead.
+type Sensor interface {
+	// Distance returns the distance in metres. When nothing is in range it
+	// returns the largest distance the sensor can measure.
+	Distance() (float64, error)
+}
@@ -0,0 +1,117 @@
+// Package distancetest has fake distance sensors, to try the collision
+// avoidance without the hardware.
+package distancetest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/gophercar/distance"
+)
+
+// Sensor is a distance.Sensor that returns the distance and error it is set to.
+type Sensor struct {
+	mu       sync.Mutex
+	distance float64
+	err      error
+	reads    int
+}
+
+// NewSensor returns a Sensor that measures d metres.
+func NewSensor(d float64) *Sensor {
+	return &Sensor{distance: d}
+}
+
+// Set sets the distance the sensor measures, and clears its error.
+func (s *Sensor) Set(d float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.distance = d
+	s.err = nil
+}
+
+// Fail makes each read fail with err until Set is called.
+func (s *Sensor) Fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Reads returns the number of times the sensor was read.
+func (s *Sensor) Reads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reads
+}
+
+// Distance returns the distance or error the sensor is set to.
+func (s *Sensor) Distance() (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reads++
+	if s.err != nil {
+		return 0, s.err
+	}
+	return s.distance, nil
+}
+
+// Func is a distance.Sensor that calls a function, such as one working out
+// the distance to an obstacle in a simulation.
+type Func func() float64
+
+// Distance returns the result of f.
+func (f Func) Distance() (float64, error) {
+	return f(), nil
+}
+
+// Pins are the GPIO pins of an HC-SR04 that sees an obstacle at Distance
+// metres. Each time the trigger pin goes low the echo pin goes high for as
+// long as the sound would take to get to the obstacle and back.
+type Pins struct {
+	Trigger, Echo string
+
+	mu       sync.Mutex
+	distance float64
+	echo     time.Time
+	trigger  byte
+}
+
+// NewPins returns Pins for an HC-SR04 with the given trigger and echo pins,
+// with an obstacle at d metres.
+func NewPins(trigger, echo string, d float64) *Pins {
+	return &Pins{Trigger: trigger, Echo: echo, distance: d}
+}
+
+// Set moves the obstacle to d metres.
+func (p *Pins) Set(d float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.distance = d
+}
+
+// DigitalWrite starts an echo when the trigger pin goes from high to low.
+func (p *Pins) DigitalWrite(pin string, level byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pin == p.Trigger {
+		if p.trigger == 1 && level == 0 {
+			p.echo = time.Now()
+		}
+		p.trigger = level
+	}
+	return nil
+}
+
+// DigitalRead returns 1 on the echo pin while the echo is on its way.
+func (p *Pins) DigitalRead(pin string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pin != p.Echo || p.echo.IsZero() {
+		return 0, nil
+	}
+	flight := time.Duration(2 * p.distance / distance.SpeedOfSound * float64(time.Second))
+	if time.Since(p.echo) < flight {
+		return 1, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,122 @@
+package distance
+
+import (
+	"sync"
+	"time"
+
+	"gobot.io/x/gobot"
+)
+
+// SpeedOfSound is the speed of sound in air at 20C, in metres/second.
+const SpeedOfSound = 343.0
+
+// Pins are the GPIO pins of an adaptor, such as the raspi.Adaptor.
+type Pins interface {
+	DigitalWrite(pin string, level byte) error
+	DigitalRead(pin string) (int, error)
+}
+
+// HCSR04Driver is a driver for the HC-SR04 ultrasonic distance sensor. The
+// echo pin gives 5V, so connect it to the Pi through a voltage divider.
+//
+// The echo pulse is timed by polling the pin, so the resolution depends on
+// how fast the adaptor reads a pin, which is about a centimetre on a Pi.
+type HCSR04Driver struct {
+	TriggerPin string
+	EchoPin    string
+
+	// MaxRange is the distance returned when no echo comes back, and Timeout
+	// how long to wait for the echo to start.
+	MaxRange float64
+	Timeout  time.Duration
+
+	name string
+	pins Pins
+	mu   sync.Mutex
+}
+
+// NewHCSR04Driver returns a driver for an HC-SR04 with the given trigger and
+// echo pins of the adaptor.
+func NewHCSR04Driver(a Pins, trigger, echo string) *HCSR04Driver {
+	return &HCSR04Driver{
+		TriggerPin: trigger,
+		EchoPin:    echo,
+		MaxRange:   4,
+		Timeout:    10 * time.Millisecond,
+		name:       gobot.DefaultName("HCSR04"),
+		pins:       a,
+	}
+}
+
+// Name returns the name of the driver.
+func (h *HCSR04Driver) Name() string { return h.name }
+
+// SetName sets the name of the driver.
+func (h *HCSR04Driver) SetName(n string) { h.name = n }
+
+// Connection returns the adaptor of the driver.
+func (h *HCSR04Driver) Connection() gobot.Connection { return h.pins.(gobot.Connection) }
+
+// Start sets the trigger pin low.
+func (h *HCSR04Driver) Start() error {
+	return h.pins.DigitalWrite(h.TriggerPin, 0)
+}
+
+// Halt does nothing.
+func (h *HCSR04Driver) Halt() error { return nil }
+
+// Distance sends a pulse and times its echo. It takes up to about 25ms when
+// nothing is in range.
+func (h *HCSR04Driver) Distance() (float64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// a trigger pulse of at least 10us
+	if err := h.pins.DigitalWrite(h.TriggerPin, 1); err != nil {
+		return 0, err
+	}
+	time.Sleep(10 * time.Microsecond)
+	if err := h.pins.DigitalWrite(h.TriggerPin, 0); err != nil {
+		return 0, err
+	}
+
+	start, err := h.waitFor(1, time.Now().Add(h.Timeout))
+	if err != nil {
+		return 0, err
+	}
+	// the echo pulse is as long as the sound takes there and back
+	end, err := h.waitFor(0, start.Add(h.echoTime()))
+	if err == ErrTimeout {
+		return h.MaxRange, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	d := end.Sub(start).Seconds() * SpeedOfSound / 2
+	if d > h.MaxRange {
+		d = h.MaxRange
+	}
+	return d, nil
+}
+
+// waitFor waits until the echo pin is at level, and returns when it got there.
+func (h *HCSR04Driver) waitFor(level int, deadline time.Time) (time.Time, error) {
+	for {
+		v, err := h.pins.DigitalRead(h.EchoPin)
+		now := time.Now()
+		if err != nil {
+			return now, err
+		}
+		if v == level {
+			return now, nil
+		}
+		if now.After(deadline) {
+			return now, ErrTimeout
+		}
+	}
+}
+
+// echoTime returns how long the echo from MaxRange takes.
+func (h *HCSR04Driver) echoTime() time.Duration {
+	return time.Duration(2 * h.MaxRange / SpeedOfSound * float64(time.Second))
+}
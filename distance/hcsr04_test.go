@@ -0,0 +1,64 @@
+package distance_test
+
+import (
+	"math"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gophercar/distance"
+	"github.com/hybridgroup/gophercar/distance/distancetest"
+)
+
+func TestHCSR04Distance(t *testing.T) {
+	tests := []struct {
+		obstacle, want float64
+	}{
+		{0.1, 0.1},
+		{0.5, 0.5},
+		{1.2, 1.2},
+		{3, 3},
+		// out of range, so the echo is cut off
+		{6, 4},
+	}
+	pins := distancetest.NewPins("11", "13", 0)
+	h := distance.NewHCSR04Driver(pins, "11", "13")
+	for _, tt := range tests {
+		pins.Set(tt.obstacle)
+
+		// the echo is timed by polling, so a reading is now and then thrown
+		// out by the test being descheduled; take the median of a few
+		var readings []float64
+		for i := 0; i < 5; i++ {
+			d, err := h.Distance()
+			if err != nil {
+				t.Fatalf("obstacle at %vm: %v", tt.obstacle, err)
+			}
+			readings = append(readings, d)
+		}
+		sort.Float64s(readings)
+		d := readings[len(readings)/2]
+		if math.Abs(d-tt.want) > 0.03 {
+			t.Errorf("obstacle at %vm: got %.3fm, want %vm", tt.obstacle, d, tt.want)
+		}
+	}
+}
+
+// deadPins never give an echo, as when the echo pin is not connected.
+type deadPins struct{}
+
+func (deadPins) DigitalWrite(pin string, level byte) error { return nil }
+func (deadPins) DigitalRead(pin string) (int, error)       { return 0, nil }
+
+func TestHCSR04Timeout(t *testing.T) {
+	h := distance.NewHCSR04Driver(deadPins{}, "11", "13")
+	h.Timeout = 5 * time.Millisecond
+
+	start := time.Now()
+	if _, err := h.Distance(); err != distance.ErrTimeout {
+		t.Errorf("got error %v, want ErrTimeout", err)
+	}
+	if took := time.Since(start); took < h.Timeout || took > 100*time.Millisecond {
+		t.Errorf("gave up after %v, with a timeout of %v", took, h.Timeout)
+	}
+}
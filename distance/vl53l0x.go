@@ -0,0 +1,355 @@
+package distance
+
+import (
+	"sync"
+	"time"
+
+	"gobot.io/x/gobot"
+	"gobot.io/x/gobot/drivers/i2c"
+)
+
+const vl53l0xAddress = 0x29
+
+// VL53L0X registers, named as in the ST API.
+const (
+	vl53l0xSysrangeStart                  = 0x00
+	vl53l0xSystemSequenceConfig           = 0x01
+	vl53l0xSystemInterruptConfigGPIO      = 0x0A
+	vl53l0xSystemInterruptClear           = 0x0B
+	vl53l0xResultInterruptStatus          = 0x13
+	vl53l0xResultRangeStatus              = 0x14
+	vl53l0xFinalRangeMinCountRateRtnLimit = 0x44
+	vl53l0xMSRCConfigControl              = 0x60
+	vl53l0xGPIOHVMuxActiveHigh            = 0x84
+	vl53l0xVHVConfigPadSCLSDAExtsupHV     = 0x89
+	vl53l0xGlobalConfigSPADEnablesRef0    = 0xB0
+	vl53l0xGlobalConfigRefEnStartSelect   = 0xB6
+	vl53l0xDynamicSPADNumRequestedRefSPAD = 0x4E
+	vl53l0xDynamicSPADRefEnStartOffset    = 0x4F
+)
+
+// vl53l0xTuning are the default tuning settings from the ST API, written as
+// register and value pairs.
+var vl53l0xTuning = [][2]byte{
+	{0xFF, 0x01}, {0x00, 0x00}, {0xFF, 0x00}, {0x09, 0x00}, {0x10, 0x00},
+	{0x11, 0x00}, {0x24, 0x01}, {0x25, 0xFF}, {0x75, 0x00}, {0xFF, 0x01},
+	{0x4E, 0x2C}, {0x48, 0x00}, {0x30, 0x20}, {0xFF, 0x00}, {0x30, 0x09},
+	{0x54, 0x00}, {0x31, 0x04}, {0x32, 0x03}, {0x40, 0x83}, {0x46, 0x25},
+	{0x60, 0x00}, {0x27, 0x00}, {0x50, 0x06}, {0x51, 0x00}, {0x52, 0x96},
+	{0x56, 0x08}, {0x57, 0x30}, {0x61, 0x00}, {0x62, 0x00}, {0x64, 0x00},
+	{0x65, 0x00}, {0x66, 0xA0}, {0xFF, 0x01}, {0x22, 0x32}, {0x47, 0x14},
+	{0x49, 0xFF}, {0x4A, 0x00}, {0xFF, 0x00}, {0x7A, 0x0A}, {0x7B, 0x00},
+	{0x78, 0x21}, {0xFF, 0x01}, {0x23, 0x34}, {0x42, 0x00}, {0x44, 0xFF},
+	{0x45, 0x26}, {0x46, 0x05}, {0x40, 0x40}, {0x0E, 0x06}, {0x20, 0x1A},
+	{0x43, 0x40}, {0xFF, 0x00}, {0x34, 0x03}, {0x35, 0x44}, {0xFF, 0x01},
+	{0x31, 0x04}, {0x4B, 0x09}, {0x4C, 0x05}, {0x4D, 0x04}, {0xFF, 0x00},
+	{0x44, 0x00}, {0x45, 0x20}, {0x47, 0x08}, {0x48, 0x28}, {0x67, 0x00},
+	{0x70, 0x04}, {0x71, 0x01}, {0x72, 0xFE}, {0x76, 0x00}, {0x77, 0x00},
+	{0xFF, 0x01}, {0x0D, 0x01}, {0xFF, 0x00}, {0x80, 0x01}, {0x01, 0xF8},
+	{0xFF, 0x01}, {0x8E, 0x01}, {0x00, 0x01}, {0xFF, 0x00}, {0x80, 0x00},
+}
+
+// VL53L0XDriver is a driver for the VL53L0X time of flight distance sensor.
+// It is set up following the Pololu library, with the default 33ms timing
+// budget, and measures continuously once started.
+type VL53L0XDriver struct {
+	// MaxRange is the distance returned when nothing is in range, and Timeout
+	// how long to wait for a measurement.
+	MaxRange float64
+	Timeout  time.Duration
+
+	name         string
+	connector    i2c.Connector
+	connection   i2c.Connection
+	stopVariable byte
+	mu           sync.Mutex
+	i2c.Config
+}
+
+// NewVL53L0XDriver returns a driver for a VL53L0X on the I2C bus of the
+// adaptor. The i2c.WithBus and i2c.WithAddress options can be given.
+func NewVL53L0XDriver(a i2c.Connector, options ...func(i2c.Config)) *VL53L0XDriver {
+	d := &VL53L0XDriver{
+		MaxRange:  2,
+		Timeout:   100 * time.Millisecond,
+		name:      gobot.DefaultName("VL53L0X"),
+		connector: a,
+		Config:    i2c.NewConfig(),
+	}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+// Name returns the name of the driver.
+func (d *VL53L0XDriver) Name() string { return d.name }
+
+// SetName sets the name of the driver.
+func (d *VL53L0XDriver) SetName(n string) { d.name = n }
+
+// Connection returns the adaptor of the driver.
+func (d *VL53L0XDriver) Connection() gobot.Connection { return d.connector.(gobot.Connection) }
+
+// Start sets up the sensor and starts continuous measurements.
+func (d *VL53L0XDriver) Start() (err error) {
+	bus := d.GetBusOrDefault(d.connector.GetDefaultBus())
+	address := d.GetAddressOrDefault(vl53l0xAddress)
+	if d.connection, err = d.connector.GetConnection(address, bus); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.init(); err != nil {
+		return err
+	}
+	return d.startContinuous()
+}
+
+// Halt stops the measurements.
+func (d *VL53L0XDriver) Halt() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.connection == nil {
+		return nil
+	}
+	return d.connection.WriteByteData(vl53l0xSysrangeStart, 0x01)
+}
+
+// Distance returns the latest measurement.
+func (d *VL53L0XDriver) Distance() (float64, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.waitInterrupt(); err != nil {
+		return 0, err
+	}
+	mm, err := d.read16(vl53l0xResultRangeStatus + 10)
+	if err != nil {
+		return 0, err
+	}
+	if err := d.connection.WriteByteData(vl53l0xSystemInterruptClear, 0x01); err != nil {
+		return 0, err
+	}
+
+	// 8190 or more means there was no target
+	m := float64(mm) / 1000
+	if mm >= 8190 || m > d.MaxRange {
+		m = d.MaxRange
+	}
+	return m, nil
+}
+
+// init is the data init, static init and reference calibration of the ST
+// API, as done by the Pololu library.
+func (d *VL53L0XDriver) init() error {
+	w := &regWriter{c: d.connection}
+
+	// use 2.8V I/O, as on most breakout boards
+	w.set(vl53l0xVHVConfigPadSCLSDAExtsupHV, 0x01)
+	w.write(0x88, 0x00)
+
+	w.write(0x80, 0x01)
+	w.write(0xFF, 0x01)
+	w.write(0x00, 0x00)
+	d.stopVariable = w.read(0x91)
+	w.write(0x00, 0x01)
+	w.write(0xFF, 0x00)
+	w.write(0x80, 0x00)
+
+	// turn off the MSRC and pre-range signal rate limit checks, and set the
+	// final range signal rate limit to 0.25 MCPS in 9.7 fixed point
+	w.set(vl53l0xMSRCConfigControl, 0x12)
+	w.write(vl53l0xFinalRangeMinCountRateRtnLimit, 0x00)
+	w.write(vl53l0xFinalRangeMinCountRateRtnLimit+1, 0x20)
+	w.write(vl53l0xSystemSequenceConfig, 0xFF)
+	if w.err != nil {
+		return w.err
+	}
+
+	if err := d.setReferenceSPADs(w); err != nil {
+		return err
+	}
+
+	for _, t := range vl53l0xTuning {
+		w.write(t[0], t[1])
+	}
+
+	// interrupt on a new sample, active low
+	w.write(vl53l0xSystemInterruptConfigGPIO, 0x04)
+	w.write(vl53l0xGPIOHVMuxActiveHigh, w.read(vl53l0xGPIOHVMuxActiveHigh)&^0x10)
+	w.write(vl53l0xSystemInterruptClear, 0x01)
+	w.write(vl53l0xSystemSequenceConfig, 0xE8)
+	if w.err != nil {
+		return w.err
+	}
+
+	// VHV and phase calibration
+	w.write(vl53l0xSystemSequenceConfig, 0x01)
+	if err := d.calibrate(w, 0x40); err != nil {
+		return err
+	}
+	w.write(vl53l0xSystemSequenceConfig, 0x02)
+	if err := d.calibrate(w, 0x00); err != nil {
+		return err
+	}
+	w.write(vl53l0xSystemSequenceConfig, 0xE8)
+	return w.err
+}
+
+// setReferenceSPADs enables the reference SPADs given in the sensor's NVM.
+func (d *VL53L0XDriver) setReferenceSPADs(w *regWriter) error {
+	w.write(0x80, 0x01)
+	w.write(0xFF, 0x01)
+	w.write(0x00, 0x00)
+	w.write(0xFF, 0x06)
+	w.set(0x83, 0x04)
+	w.write(0xFF, 0x07)
+	w.write(0x81, 0x01)
+	w.write(0x80, 0x01)
+	w.write(0x94, 0x6B)
+	w.write(0x83, 0x00)
+	if err := d.poll(w, 0x83, 0xFF); err != nil {
+		return err
+	}
+	w.write(0x83, 0x01)
+	info := w.read(0x92)
+	w.write(0x81, 0x00)
+	w.write(0xFF, 0x06)
+	w.write(0x83, w.read(0x83)&^0x04)
+	w.write(0xFF, 0x01)
+	w.write(0x00, 0x01)
+	w.write(0xFF, 0x00)
+	w.write(0x80, 0x00)
+
+	count := int(info & 0x7F)
+	first := 0
+	if info&0x80 != 0 {
+		// aperture SPADs start at 12
+		first = 12
+	}
+
+	spads := make([]byte, 6)
+	w.readBlock(vl53l0xGlobalConfigSPADEnablesRef0, spads)
+	w.write(0xFF, 0x01)
+	w.write(vl53l0xDynamicSPADRefEnStartOffset, 0x00)
+	w.write(vl53l0xDynamicSPADNumRequestedRefSPAD, 0x2C)
+	w.write(0xFF, 0x00)
+	w.write(vl53l0xGlobalConfigRefEnStartSelect, 0xB4)
+
+	enabled := 0
+	for i := 0; i < 48; i++ {
+		bit := byte(1) << uint(i%8)
+		if i < first || enabled == count {
+			spads[i/8] &^= bit
+		} else if spads[i/8]&bit != 0 {
+			enabled++
+		}
+	}
+	w.writeBlock(vl53l0xGlobalConfigSPADEnablesRef0, spads)
+	return w.err
+}
+
+// calibrate runs a single reference calibration.
+func (d *VL53L0XDriver) calibrate(w *regWriter, vhvInit byte) error {
+	w.write(vl53l0xSysrangeStart, 0x01|vhvInit)
+	if w.err != nil {
+		return w.err
+	}
+	if err := d.waitInterrupt(); err != nil {
+		return err
+	}
+	w.write(vl53l0xSystemInterruptClear, 0x01)
+	w.write(vl53l0xSysrangeStart, 0x00)
+	return w.err
+}
+
+// startContinuous starts measuring back to back.
+func (d *VL53L0XDriver) startContinuous() error {
+	w := &regWriter{c: d.connection}
+	w.write(0x80, 0x01)
+	w.write(0xFF, 0x01)
+	w.write(0x00, 0x00)
+	w.write(0x91, d.stopVariable)
+	w.write(0x00, 0x01)
+	w.write(0xFF, 0x00)
+	w.write(0x80, 0x00)
+	w.write(vl53l0xSysrangeStart, 0x02)
+	return w.err
+}
+
+// waitInterrupt waits for a measurement to be ready.
+func (d *VL53L0XDriver) waitInterrupt() error {
+	return d.poll(&regWriter{c: d.connection}, vl53l0xResultInterruptStatus, 0x07)
+}
+
+// poll waits until one of the mask bits of a register is set.
+func (d *VL53L0XDriver) poll(w *regWriter, reg, mask byte) error {
+	deadline := time.Now().Add(d.Timeout)
+	for {
+		v := w.read(reg)
+		if w.err != nil {
+			return w.err
+		}
+		if v&mask != 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return ErrTimeout
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// read16 reads a big endian 16 bit register.
+func (d *VL53L0XDriver) read16(reg byte) (uint16, error) {
+	w := &regWriter{c: d.connection}
+	buf := make([]byte, 2)
+	w.readBlock(reg, buf)
+	return uint16(buf[0])<<8 | uint16(buf[1]), w.err
+}
+
+// regWriter reads and writes registers until the first error, which it
+// keeps, so that long setup sequences can be written without checking each
+// step.
+type regWriter struct {
+	c   i2c.Connection
+	err error
+}
+
+func (w *regWriter) write(reg, v byte) {
+	if w.err == nil {
+		w.err = w.c.WriteByteData(reg, v)
+	}
+}
+
+func (w *regWriter) read(reg byte) byte {
+	if w.err != nil {
+		return 0
+	}
+	var v byte
+	v, w.err = w.c.ReadByteData(reg)
+	return v
+}
+
+// set sets bits of a register.
+func (w *regWriter) set(reg, bits byte) {
+	w.write(reg, w.read(reg)|bits)
+}
+
+func (w *regWriter) readBlock(reg byte, buf []byte) {
+	if w.err != nil {
+		return
+	}
+	if _, w.err = w.c.Write([]byte{reg}); w.err != nil {
+		return
+	}
+	_, w.err = w.c.Read(buf)
+}
+
+func (w *regWriter) writeBlock(reg byte, buf []byte) {
+	if w.err == nil {
+		_, w.err = w.c.Write(append([]byte{reg}, buf...))
+	}
+}
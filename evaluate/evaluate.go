@@ -0,0 +1,170 @@
+// Package evaluate compares what a pilot would have done with the recorded
+// frames of a tub against what the driver did, so that a change to a pilot
+// can be checked before it is tried on the car.
+package evaluate
+
+import (
+	"image/color"
+	"math"
+	"strconv"
+
+	"github.com/fogleman/gg"
+)
+
+// Result is what the pilot did with one record.
+type Result struct {
+	Index int `json:"index"`
+
+	// Angle and Throttle were recorded, and Steering and PilotThrottle are
+	// from the pilot. OK is false if the pilot could not decide, in which
+	// case its last output is kept, as on the car.
+	Angle         float64 `json:"angle"`
+	Throttle      float64 `json:"throttle"`
+	Steering      float64 `json:"steering"`
+	PilotThrottle float64 `json:"pilot_throttle"`
+	OK            bool    `json:"ok"`
+}
+
+// Errors sums up how far the pilot was from the driver.
+type Errors struct {
+	Records int `json:"records"`
+
+	// Lost is the number of records the pilot could not decide on, such as
+	// when the line was lost.
+	Lost int `json:"lost"`
+
+	// SteeringMAE and ThrottleMAE are the mean absolute errors.
+	SteeringMAE float64 `json:"steering_mae"`
+	ThrottleMAE float64 `json:"throttle_mae"`
+}
+
+// Compare returns the Errors of the results.
+func Compare(results []Result) Errors {
+	e := Errors{Records: len(results)}
+	if len(results) == 0 {
+		return e
+	}
+	for _, r := range results {
+		if !r.OK {
+			e.Lost++
+		}
+		e.SteeringMAE += math.Abs(r.Steering - r.Angle)
+		e.ThrottleMAE += math.Abs(r.PilotThrottle - r.Throttle)
+	}
+	e.SteeringMAE /= float64(len(results))
+	e.ThrottleMAE /= float64(len(results))
+	return e
+}
+
+// Segment is the Errors of a run of records, with a plot of them.
+type Segment struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+	Errors
+	Plot string `json:"plot,omitempty"`
+}
+
+// Report is the result of running a pilot over a tub.
+type Report struct {
+	Tub   string `json:"tub"`
+	Pilot string `json:"pilot"`
+	Errors
+	Segments []Segment `json:"segments"`
+}
+
+// Segments splits the results into runs of size, and returns the Errors of
+// each.
+func Segments(results []Result, size int) []Segment {
+	var segments []Segment
+	for start := 0; start < len(results); start += size {
+		end := start + size
+		if end > len(results) {
+			end = len(results)
+		}
+		segments = append(segments, Segment{
+			From:   results[start].Index,
+			To:     results[end-1].Index,
+			Errors: Compare(results[start:end]),
+		})
+	}
+	return segments
+}
+
+// Colours of the plots.
+var (
+	RecordedColor = color.RGBA{G: 160, A: 255}
+	PilotColor    = color.RGBA{R: 220, A: 255}
+	LostColor     = color.RGBA{R: 255, G: 220, B: 220, A: 255}
+)
+
+// Plot draws the recorded and the pilot steering and throttle of the results
+// against the record index, and saves it as a PNG.
+func Plot(results []Result, path string) error {
+	const (
+		width, height = 1000, 500
+		margin        = 40
+	)
+	dc := gg.NewContext(width, height)
+	dc.SetColor(color.White)
+	dc.Clear()
+
+	panelHeight := float64(height-3*margin) / 2
+	panels := []struct {
+		name          string
+		top           float64
+		recorded, got func(r Result) float64
+	}{
+		{"steering", margin, func(r Result) float64 { return r.Angle }, func(r Result) float64 { return r.Steering }},
+		{"throttle", 2*margin + panelHeight, func(r Result) float64 { return r.Throttle }, func(r Result) float64 { return r.PilotThrottle }},
+	}
+
+	n := float64(len(results))
+	x := func(i int) float64 { return margin + float64(i)/math.Max(n-1, 1)*(width-2*margin) }
+	for _, p := range panels {
+		y := func(v float64) float64 { return p.top + (1-math.Max(-1, math.Min(v, 1)))/2*panelHeight }
+
+		// shade where the pilot could not decide
+		dc.SetColor(LostColor)
+		for i, r := range results {
+			if !r.OK {
+				dc.DrawRectangle(x(i)-0.5, p.top, math.Max(1, x(i+1)-x(i)), panelHeight)
+			}
+		}
+		dc.Fill()
+
+		dc.SetColor(color.Gray{Y: 128})
+		dc.SetLineWidth(1)
+		dc.DrawRectangle(margin, p.top, width-2*margin, panelHeight)
+		dc.DrawLine(margin, y(0), width-margin, y(0))
+		dc.Stroke()
+		dc.SetColor(color.Black)
+		dc.DrawString(p.name, margin, p.top-6)
+		dc.DrawStringAnchored("1", margin-6, y(1), 1, 0.5)
+		dc.DrawStringAnchored("-1", margin-6, y(-1), 1, 0.5)
+
+		for _, line := range []struct {
+			c     color.Color
+			value func(r Result) float64
+		}{{RecordedColor, p.recorded}, {PilotColor, p.got}} {
+			dc.SetColor(line.c)
+			dc.SetLineWidth(1.5)
+			for i, r := range results {
+				dc.LineTo(x(i), y(line.value(r)))
+			}
+			dc.Stroke()
+		}
+	}
+
+	if len(results) > 0 {
+		dc.SetColor(color.Black)
+		first, last := results[0].Index, results[len(results)-1].Index
+		dc.DrawStringAnchored(strconv.Itoa(first), margin, height-margin/2, 0, 0.5)
+		dc.DrawStringAnchored(strconv.Itoa(last), width-margin, height-margin/2, 1, 0.5)
+	}
+	dc.SetColor(RecordedColor)
+	dc.DrawStringAnchored("recorded", width-margin-80, margin-6, 1, 0)
+	dc.SetColor(PilotColor)
+	dc.DrawStringAnchored("pilot", width-margin, margin-6, 1, 0)
+
+	return dc.SavePNG(path)
+}
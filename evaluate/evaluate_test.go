@@ -0,0 +1,82 @@
+package evaluate
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func near(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+// testResults has the pilot off by 0.1, 0.2, ... in steering and half as much
+// in throttle, and lost on every third record.
+func testResults(n int) []Result {
+	results := make([]Result, n)
+	for i := range results {
+		off := 0.1 * float64(i+1)
+		results[i] = Result{
+			Index:         100 + i,
+			Angle:         0.2,
+			Throttle:      0.5,
+			Steering:      0.2 - off,
+			PilotThrottle: 0.5 + off/2,
+			OK:            i%3 != 2,
+		}
+	}
+	return results
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		results  []Result
+		lost     int
+		steering float64
+		throttle float64
+	}{
+		{"empty", nil, 0, 0, 0},
+		{"one", testResults(1), 0, 0.1, 0.05},
+		{"four", testResults(4), 1, 0.25, 0.125},
+	}
+	for _, tt := range tests {
+		e := Compare(tt.results)
+		if e.Records != len(tt.results) || e.Lost != tt.lost {
+			t.Errorf("%s: %d records, %d lost, want %d and %d", tt.name, e.Records, e.Lost, len(tt.results), tt.lost)
+		}
+		if !near(e.SteeringMAE, tt.steering, 1e-9) || !near(e.ThrottleMAE, tt.throttle, 1e-9) {
+			t.Errorf("%s: MAE %.4f, %.4f, want %.4f, %.4f", tt.name, e.SteeringMAE, e.ThrottleMAE, tt.steering, tt.throttle)
+		}
+	}
+}
+
+func TestSegments(t *testing.T) {
+	results := testResults(5)
+	tests := []struct {
+		name    string
+		results []Result
+		size    int
+		want    []Segment
+	}{
+		{"empty", nil, 2, nil},
+		{"uneven", results, 2, []Segment{
+			{From: 100, To: 101, Errors: Compare(results[:2])},
+			{From: 102, To: 103, Errors: Compare(results[2:4])},
+			{From: 104, To: 104, Errors: Compare(results[4:])},
+		}},
+		{"longer than the tub", results, 10, []Segment{
+			{From: 100, To: 104, Errors: Compare(results)},
+		}},
+	}
+	for _, tt := range tests {
+		got := Segments(tt.results, tt.size)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	if e := Segments(results, 2)[2].Errors; e.Records != 1 || e.Lost != 0 || !near(e.SteeringMAE, 0.5, 1e-9) {
+		t.Errorf("the last segment has %+v", e)
+	}
+}
@@ -0,0 +1,294 @@
+package gym
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// ErrClosed is returned once the simulator has closed the connection.
+var ErrClosed = errors.New("gym: connection closed")
+
+var errTimeout = errors.New("gym: timed out")
+
+// Client is a connection to the simulator. It is a car.Actuator that drives
+// the simulated car, and a vision.FrameSource that reads its camera.
+type Client struct {
+	// OnTelemetry, if set before loading a scene, is called with each
+	// telemetry message, without its image.
+	OnTelemetry func(t Telemetry)
+
+	// WriteTimeout is how long to wait to send a message.
+	WriteTimeout time.Duration
+
+	conn net.Conn
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	telemetry Telemetry
+	frame     []byte
+	frames    int
+	steering  float64
+	loaded    bool
+	scenes    []string
+	err       error
+}
+
+// Dial connects to the simulator at addr.
+func Dial(addr string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		WriteTimeout: time.Second,
+		conn:         conn,
+		w:            bufio.NewWriter(conn),
+	}
+	c.cond = sync.NewCond(&c.mu)
+	go c.receive()
+	return c, nil
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// SceneNames returns the tracks the simulator can load.
+func (c *Client) SceneNames(timeout time.Duration) ([]string, error) {
+	c.mu.Lock()
+	c.scenes = nil
+	c.mu.Unlock()
+	if err := c.send(Message{Type: MsgGetSceneNames}); err != nil {
+		return nil, err
+	}
+
+	var scenes []string
+	err := c.wait(timeout, func() bool {
+		scenes = c.scenes
+		return scenes != nil
+	})
+	return scenes, err
+}
+
+// Load loads a track and waits for the car to be put on it.
+func (c *Client) Load(scene string, timeout time.Duration) error {
+	c.mu.Lock()
+	c.loaded = false
+	c.mu.Unlock()
+	if err := c.send(LoadScene{Type: MsgLoadScene, Scene: scene}); err != nil {
+		return err
+	}
+	err := c.wait(timeout, func() bool { return c.loaded })
+	if err != nil {
+		return fmt.Errorf("loading %s: %v", scene, err)
+	}
+	return nil
+}
+
+// Reset puts the car back at the start of the track.
+func (c *Client) Reset() error {
+	return c.send(Message{Type: MsgResetCar})
+}
+
+// ExitScene goes back to the menu of the simulator.
+func (c *Client) ExitScene() error {
+	return c.send(Message{Type: MsgExitScene})
+}
+
+// SetSteering sets the steering, from -1 (left) to 1 (right). It is sent to
+// the simulator with the next throttle.
+func (c *Client) SetSteering(steering float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.steering = steering
+	return c.err
+}
+
+// SetThrottle sends the throttle, from -1 (reverse) to 1 (forward), along
+// with the last steering.
+func (c *Client) SetThrottle(throttle float64) error {
+	c.mu.Lock()
+	steering := c.steering
+	c.mu.Unlock()
+
+	return c.send(Control{
+		Type:     MsgControl,
+		Steering: strconv.FormatFloat(steering, 'f', 4, 64),
+		Throttle: strconv.FormatFloat(throttle, 'f', 4, 64),
+		Brake:    "0.0",
+	})
+}
+
+// Telemetry returns the last telemetry from the simulator, without its image.
+func (c *Client) Telemetry() Telemetry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.telemetry
+}
+
+// NextFrame waits for a camera image newer than the last one returned, and
+// returns it as a JPEG.
+func (c *Client) NextFrame(timeout time.Duration) ([]byte, error) {
+	var frame []byte
+	err := c.wait(timeout, func() bool {
+		if c.frames == 0 {
+			return false
+		}
+		frame, c.frames = c.frame, 0
+		return true
+	})
+	return frame, err
+}
+
+// Read waits for the next camera image and decodes it into m. It returns
+// false once the connection is closed.
+func (c *Client) Read(m *gocv.Mat) bool {
+	for {
+		buf, err := c.NextFrame(time.Second)
+		if err == errTimeout {
+			continue
+		}
+		if err != nil {
+			return false
+		}
+
+		img, err := gocv.IMDecode(buf, gocv.IMReadColor)
+		if err != nil {
+			continue
+		}
+		if img.Empty() {
+			img.Close()
+			continue
+		}
+		img.CopyTo(m)
+		img.Close()
+		return true
+	}
+}
+
+// wait waits until done returns true, which is called with c.mu held.
+func (c *Client) wait(timeout time.Duration, done func() bool) error {
+	timer := time.AfterFunc(timeout, func() {
+		c.mu.Lock()
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	})
+	defer timer.Stop()
+	deadline := time.Now().Add(timeout)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for !done() {
+		if c.err != nil {
+			return c.err
+		}
+		if !time.Now().Before(deadline) {
+			return errTimeout
+		}
+		c.cond.Wait()
+	}
+	return nil
+}
+
+// send writes a message followed by a newline.
+func (c *Client) send(msg interface{}) error {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(c.WriteTimeout))
+	c.w.Write(buf)
+	c.w.WriteByte('\n')
+	return c.w.Flush()
+}
+
+// receive reads messages until the connection is closed or a message cannot
+// be read. The simulator ends each message with a newline, but a json.Decoder
+// does not rely on that.
+func (c *Client) receive() {
+	dec := json.NewDecoder(bufio.NewReader(c.conn))
+	var err error
+	for err == nil {
+		var raw json.RawMessage
+		if err = dec.Decode(&raw); err == nil {
+			err = c.handle(raw)
+		}
+	}
+
+	if err == io.EOF {
+		err = ErrClosed
+	}
+	c.mu.Lock()
+	c.err = err
+	c.cond.Broadcast()
+	c.mu.Unlock()
+}
+
+func (c *Client) handle(raw json.RawMessage) error {
+	var msg Message
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return err
+	}
+
+	switch msg.Type {
+	case MsgTelemetry:
+		var t Telemetry
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return err
+		}
+		// a broken image is dropped, but the rest of the telemetry is still
+		// worth having
+		frame, err := base64.StdEncoding.DecodeString(t.Image)
+		badImage := err != nil
+		t.Image = ""
+		if c.OnTelemetry != nil {
+			c.OnTelemetry(t)
+		}
+
+		c.mu.Lock()
+		c.telemetry = t
+		if !badImage {
+			c.frame = frame
+			c.frames++
+		}
+		c.cond.Broadcast()
+		c.mu.Unlock()
+
+	case MsgCarLoaded:
+		c.mu.Lock()
+		c.loaded = true
+		c.cond.Broadcast()
+		c.mu.Unlock()
+
+	case MsgSceneNames:
+		var names SceneNames
+		if err := json.Unmarshal(raw, &names); err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.scenes = names.Scenes
+		if c.scenes == nil {
+			c.scenes = []string{}
+		}
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package gym_test
+
+import (
+	"bytes"
+	"fmt"
+	"image/jpeg"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gophercar/gym"
+	"github.com/hybridgroup/gophercar/gym/gymtest"
+)
+
+const timeout = 2 * time.Second
+
+func dial(t *testing.T) (*gymtest.Server, *gym.Client) {
+	server, err := gymtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Interval = 10 * time.Millisecond
+	c, err := gym.Dial(server.Addr)
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+	return server, c
+}
+
+func TestSceneNames(t *testing.T) {
+	server, c := dial(t)
+	defer server.Close()
+	defer c.Close()
+
+	server.Scenes = []string{"generated_road", "warehouse"}
+	scenes, err := c.SceneNames(timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(scenes) != fmt.Sprint(server.Scenes) {
+		t.Errorf("got scenes %v, want %v", scenes, server.Scenes)
+	}
+}
+
+func TestLoadAndDrive(t *testing.T) {
+	server, c := dial(t)
+	defer server.Close()
+	defer c.Close()
+
+	if _, err := c.NextFrame(50 * time.Millisecond); err == nil {
+		t.Error("got a frame before a scene was loaded")
+	}
+	if err := c.Load(gym.DefaultScene, timeout); err != nil {
+		t.Fatal(err)
+	}
+
+	frame, err := c.NextFrame(timeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(frame)); err != nil {
+		t.Errorf("the frame is not a JPEG: %v", err)
+	}
+	if c.Telemetry().Image != "" {
+		t.Error("the image was left in the telemetry")
+	}
+
+	if err := c.SetSteering(0.25); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetThrottle(0.5); err != nil {
+		t.Fatal(err)
+	}
+
+	// the fake simulator reports the controls it was last sent
+	deadline := time.Now().Add(timeout)
+	for {
+		if _, err := c.NextFrame(timeout); err != nil {
+			t.Fatal(err)
+		}
+		tm := c.Telemetry()
+		if tm.Steering == 0.25 && tm.Throttle == 0.5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("the simulator has steering %v and throttle %v, want 0.25 and 0.5", tm.Steering, tm.Throttle)
+		}
+	}
+
+	received := fmt.Sprint(server.Received())
+	if want := fmt.Sprint([]string{gym.MsgLoadScene, gym.MsgControl}); received != want {
+		t.Errorf("the simulator received %s, want %s", received, want)
+	}
+}
+
+// serveRaw starts a listener that writes out to the first connection and
+// then closes it.
+func serveRaw(t *testing.T, out string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		conn.Write([]byte(out))
+		conn.Close()
+	}()
+	return ln.Addr().String()
+}
+
+func TestReceiveErrors(t *testing.T) {
+	good := `{"msg_type":"telemetry","image":"AAEC","speed":1}` + "\n"
+	bad := `{"msg_type":"telemetry","image":"not base64!","speed":2}` + "\n"
+	tests := []struct {
+		name   string
+		out    string
+		frames int
+		speed  float64
+		closed bool
+	}{
+		{"closed by the simulator", good, 1, 1, true},
+		{"broken image skipped", good + bad, 1, 2, true},
+		{"broken message", good + "{\"msg_type\":}\n", 1, 1, false},
+	}
+	for _, tt := range tests {
+		c, err := gym.Dial(serveRaw(t, tt.out))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		frames := 0
+		for {
+			_, err = c.NextFrame(timeout)
+			if err != nil {
+				break
+			}
+			frames++
+		}
+		if frames != tt.frames {
+			t.Errorf("%s: got %d frames, want %d", tt.name, frames, tt.frames)
+		}
+		if speed := c.Telemetry().Speed; speed != tt.speed {
+			t.Errorf("%s: got speed %v, want %v", tt.name, speed, tt.speed)
+		}
+		if closed := err == gym.ErrClosed; closed != tt.closed {
+			t.Errorf("%s: got error %v", tt.name, err)
+		}
+		c.Close()
+	}
+}
@@ -0,0 +1,234 @@
+// Package gymtest is a stand-in for the Donkeycar simulator, for trying out
+// gym clients without it. It speaks the same protocol, and drives a car
+// round a track simulated by package sim.
+package gymtest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image/jpeg"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/gophercar/gym"
+	"github.com/hybridgroup/gophercar/sim"
+)
+
+// Server is a fake simulator listening on a local port.
+type Server struct {
+	Addr string
+
+	// Scenes are the names of the tracks it offers. They are all the same
+	// oval.
+	Scenes []string
+
+	// Interval is the time between telemetry messages.
+	Interval time.Duration
+
+	ln net.Listener
+
+	mu       sync.Mutex
+	received []string
+	conns    map[net.Conn]bool
+	closed   bool
+}
+
+// NewServer starts a Server on a free port.
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		Addr:     ln.Addr().String(),
+		Scenes:   []string{gym.DefaultScene},
+		Interval: 50 * time.Millisecond,
+		ln:       ln,
+		conns:    make(map[net.Conn]bool),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// Received returns the types of the messages received so far.
+func (s *Server) Received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.received...)
+}
+
+// Close stops the Server and closes its connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	return s.ln.Close()
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			conn.Close()
+		} else {
+			s.conns[conn] = true
+			go s.serve(conn)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// session is a client connected to the Server.
+type session struct {
+	server *Server
+	conn   net.Conn
+
+	wmu sync.Mutex
+	w   *bufio.Writer
+
+	sim  *sim.Sim
+	done chan struct{}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	ss := &session{
+		server: s,
+		conn:   conn,
+		w:      bufio.NewWriter(conn),
+	}
+	defer func() {
+		ss.unload()
+		conn.Close()
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	ss.send(gym.Message{Type: gym.MsgSceneSelectionReady})
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return
+		}
+		var msg gym.Message
+		if err := json.Unmarshal(raw, &msg); err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.received = append(s.received, msg.Type)
+		s.mu.Unlock()
+
+		switch msg.Type {
+		case gym.MsgGetSceneNames:
+			ss.send(gym.SceneNames{Type: gym.MsgSceneNames, Scenes: s.Scenes})
+		case gym.MsgLoadScene:
+			ss.load()
+		case gym.MsgExitScene:
+			ss.unload()
+			ss.send(gym.Message{Type: gym.MsgSceneSelectionReady})
+		case gym.MsgResetCar:
+			if ss.sim != nil {
+				ss.sim.Reset()
+			}
+		case gym.MsgControl:
+			var ctl gym.Control
+			if err := json.Unmarshal(raw, &ctl); err != nil {
+				return
+			}
+			if ss.sim != nil {
+				steering, _ := strconv.ParseFloat(ctl.Steering, 64)
+				throttle, _ := strconv.ParseFloat(ctl.Throttle, 64)
+				ss.sim.SetSteering(steering)
+				ss.sim.SetThrottle(throttle)
+			}
+		case gym.MsgQuit:
+			return
+		}
+	}
+}
+
+// load puts a car on an oval and starts sending telemetry.
+func (ss *session) load() {
+	ss.unload()
+	ss.sim = sim.New(sim.Oval(4, 1.5))
+	ss.done = make(chan struct{})
+	ss.send(gym.Message{Type: gym.MsgCarLoaded})
+	go ss.telemetry(ss.sim, ss.done)
+}
+
+func (ss *session) unload() {
+	if ss.sim != nil {
+		close(ss.done)
+		ss.sim.Close()
+		ss.sim = nil
+	}
+}
+
+// telemetry moves the car on and sends what it sees, until done is closed.
+func (ss *session) telemetry(s *sim.Sim, done chan struct{}) {
+	ticker := time.NewTicker(ss.server.Interval)
+	defer ticker.Stop()
+	var buf bytes.Buffer
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		s.Advance(ss.server.Interval)
+		st := s.Status()
+		buf.Reset()
+		if err := jpeg.Encode(&buf, s.Render(nil), nil); err != nil {
+			return
+		}
+
+		hit := "none"
+		if !st.OnTrack {
+			hit = "wall"
+		}
+		cte, _ := s.Track.CTE(st.Pose.X, st.Pose.Y)
+
+		// the simulator is y up, so the floor is x, z
+		err := ss.send(gym.Telemetry{
+			Type:     gym.MsgTelemetry,
+			Steering: st.Steering,
+			Throttle: st.Throttle,
+			Speed:    st.Speed,
+			Image:    base64.StdEncoding.EncodeToString(buf.Bytes()),
+			Hit:      hit,
+			X:        st.Pose.X,
+			Z:        st.Pose.Y,
+			CTE:      cte,
+			Time:     st.Time.Seconds(),
+		})
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (ss *session) send(msg interface{}) error {
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ss.wmu.Lock()
+	defer ss.wmu.Unlock()
+	ss.w.Write(buf)
+	ss.w.WriteByte('\n')
+	return ss.w.Flush()
+}
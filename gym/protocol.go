@@ -0,0 +1,92 @@
+// Package gym connects a car to the Donkeycar simulator (the Donkey Gym), so
+// that pilots can be tried and compared on the same tracks as Donkeycars.
+//
+// The simulator talks JSON over TCP, by default on port 9091. Each message is
+// an object with a msg_type, and ends with a newline. The simulator sends
+// telemetry, with the camera image as a base64 JPEG, as fast as it renders,
+// and the client sends control messages with the steering and throttle.
+package gym
+
+// DefaultAddress is where the simulator listens.
+const DefaultAddress = "127.0.0.1:9091"
+
+// DefaultScene is the track loaded by default.
+const DefaultScene = "generated_track"
+
+// Message types.
+const (
+	MsgSceneSelectionReady = "scene_selection_ready"
+	MsgSceneNames          = "scene_names"
+	MsgCarLoaded           = "car_loaded"
+	MsgNeedCarConfig       = "need_car_config"
+	MsgTelemetry           = "telemetry"
+
+	MsgGetSceneNames = "get_scene_names"
+	MsgLoadScene     = "load_scene"
+	MsgControl       = "control"
+	MsgResetCar      = "reset_car"
+	MsgExitScene     = "exit_scene"
+	MsgQuit          = "quit_app"
+)
+
+// Message is the part common to all messages.
+type Message struct {
+	Type string `json:"msg_type"`
+}
+
+// Telemetry is sent by the simulator for each frame.
+type Telemetry struct {
+	Type string `json:"msg_type"`
+
+	// Steering and Throttle are as last sent to the car, and Speed is in
+	// metres per second.
+	Steering float64 `json:"steering_angle"`
+	Throttle float64 `json:"throttle"`
+	Speed    float64 `json:"speed"`
+
+	// Image is a base64 encoded JPEG from the camera.
+	Image string `json:"image"`
+
+	// Hit is the name of what the car has hit, or "none".
+	Hit string `json:"hit"`
+
+	// Position in metres, and CTE the cross track error: how far the car
+	// is from the centre of the track.
+	X   float64 `json:"pos_x"`
+	Y   float64 `json:"pos_y"`
+	Z   float64 `json:"pos_z"`
+	CTE float64 `json:"cte"`
+
+	// Time is the simulation time in seconds.
+	Time float64 `json:"time"`
+
+	// ActiveNode is the node of the track the car is at, out of TotalNodes.
+	ActiveNode int `json:"activeNode"`
+	TotalNodes int `json:"totalNodes"`
+}
+
+// Crashed returns true if the car has hit something.
+func (t *Telemetry) Crashed() bool {
+	return t.Hit != "" && t.Hit != "none"
+}
+
+// SceneNames lists the tracks the simulator can load.
+type SceneNames struct {
+	Type   string   `json:"msg_type"`
+	Scenes []string `json:"scene_names"`
+}
+
+// LoadScene asks the simulator to load a track.
+type LoadScene struct {
+	Type  string `json:"msg_type"`
+	Scene string `json:"scene_name"`
+}
+
+// Control sets the steering and throttle. The simulator expects the numbers
+// as strings.
+type Control struct {
+	Type     string `json:"msg_type"`
+	Steering string `json:"steering"`
+	Throttle string `json:"throttle"`
+	Brake    string `json:"brake"`
+}
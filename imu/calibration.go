@@ -0,0 +1,86 @@
+package imu
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Calibration holds the offsets that are removed from every MPU6050 reading,
+// along with the noise that was measured while calibrating.
+type Calibration struct {
+	AccelOffset Vector `json:"accel_offset"`
+	GyroOffset  Vector `json:"gyro_offset"`
+
+	// AccelNoise and GyroNoise are the standard deviation of each axis while
+	// the car was standing still.
+	AccelNoise Vector `json:"accel_noise"`
+	GyroNoise  Vector `json:"gyro_noise"`
+
+	// Samples is the number of samples the calibration was computed from.
+	// Zero means the sensor has not been calibrated.
+	Samples int `json:"samples"`
+}
+
+// Apply returns the reading with the calibration offsets removed.
+func (c Calibration) Apply(r Reading) Reading {
+	r.Accel = r.Accel.sub(c.AccelOffset)
+	r.Gyro = r.Gyro.sub(c.GyroOffset)
+	return r
+}
+
+// Calibrate collects a number of samples from the Sensor, one every interval,
+// and computes the offsets and noise of each axis. The car must be standing
+// still on level ground, so that the only acceleration measured is 1g of
+// gravity along Z.
+func Calibrate(s Sensor, samples int, interval time.Duration) (Calibration, error) {
+	if samples < 1 {
+		return Calibration{}, errors.New("imu: calibrating needs at least one sample")
+	}
+
+	accel := make([]Vector, 0, samples)
+	gyro := make([]Vector, 0, samples)
+	for n := 0; n < samples; n++ {
+		r, err := s.Read()
+		if err != nil {
+			return Calibration{}, err
+		}
+		accel = append(accel, r.Accel)
+		gyro = append(gyro, r.Gyro)
+		time.Sleep(interval)
+	}
+
+	accelMean, accelNoise := stats(accel)
+	gyroMean, gyroNoise := stats(gyro)
+	accelMean.Z -= 1
+
+	return Calibration{
+		AccelOffset: accelMean,
+		GyroOffset:  gyroMean,
+		AccelNoise:  accelNoise,
+		GyroNoise:   gyroNoise,
+		Samples:     samples,
+	}, nil
+}
+
+// stats returns the mean and standard deviation of each axis.
+func stats(vs []Vector) (mean, stddev Vector) {
+	if len(vs) == 0 {
+		return
+	}
+
+	n := float64(len(vs))
+	for _, v := range vs {
+		mean.X += v.X / n
+		mean.Y += v.Y / n
+		mean.Z += v.Z / n
+	}
+	for _, v := range vs {
+		d := v.sub(mean)
+		stddev.X += d.X * d.X / n
+		stddev.Y += d.Y * d.Y / n
+		stddev.Z += d.Z * d.Z / n
+	}
+	stddev = Vector{X: math.Sqrt(stddev.X), Y: math.Sqrt(stddev.Y), Z: math.Sqrt(stddev.Z)}
+	return
+}
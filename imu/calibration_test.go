@@ -0,0 +1,79 @@
+package imu
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeSensor returns its readings in turn, then fails.
+type fakeSensor struct {
+	readings []Reading
+	err      error
+}
+
+func (s *fakeSensor) Read() (Reading, error) {
+	if len(s.readings) == 0 {
+		return Reading{}, s.err
+	}
+	r := s.readings[0]
+	s.readings = s.readings[1:]
+	return r, nil
+}
+
+func TestCalibrate(t *testing.T) {
+	s := &fakeSensor{readings: []Reading{
+		{Accel: Vector{X: 0.02, Y: -0.01, Z: 1.05}, Gyro: Vector{X: 1, Y: -2, Z: 0.5}},
+		{Accel: Vector{X: 0.04, Y: -0.03, Z: 1.07}, Gyro: Vector{X: 3, Y: -2, Z: 1.5}},
+	}}
+	c, err := Calibrate(s, 2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		got, want Vector
+	}{
+		{"accel offset", c.AccelOffset, Vector{X: 0.03, Y: -0.02, Z: 0.06}},
+		{"gyro offset", c.GyroOffset, Vector{X: 2, Y: -2, Z: 1}},
+		{"accel noise", c.AccelNoise, Vector{X: 0.01, Y: 0.01, Z: 0.01}},
+		{"gyro noise", c.GyroNoise, Vector{X: 1, Y: 0, Z: 0.5}},
+	}
+	for _, tt := range tests {
+		if !near(tt.got.X, tt.want.X, 1e-9) || !near(tt.got.Y, tt.want.Y, 1e-9) || !near(tt.got.Z, tt.want.Z, 1e-9) {
+			t.Errorf("%s is %+v, want %+v", tt.name, tt.got, tt.want)
+		}
+	}
+	if c.Samples != 2 {
+		t.Errorf("got %d samples, want 2", c.Samples)
+	}
+
+	// a level reading comes out as 1g straight down
+	r := c.Apply(Reading{Accel: Vector{X: 0.03, Y: -0.02, Z: 1.06}, Gyro: Vector{X: 2, Y: -2, Z: 1}})
+	if !near(r.Accel.X, 0, 1e-9) || !near(r.Accel.Y, 0, 1e-9) || !near(r.Accel.Z, 1, 1e-9) || r.Gyro != (Vector{}) {
+		t.Errorf("calibrated reading is %+v", r)
+	}
+}
+
+func TestCalibrateErrors(t *testing.T) {
+	level := Reading{Accel: Vector{Z: 1}}
+	failed := errors.New("i2c read failed")
+	tests := []struct {
+		name    string
+		samples int
+		sensor  *fakeSensor
+	}{
+		{"no samples", 0, &fakeSensor{readings: []Reading{level}}},
+		{"negative samples", -5, &fakeSensor{readings: []Reading{level}}},
+		{"read fails", 3, &fakeSensor{readings: []Reading{level}, err: failed}},
+	}
+	for _, tt := range tests {
+		c, err := Calibrate(tt.sensor, tt.samples, 0)
+		if err == nil {
+			t.Errorf("%s: no error, got %+v", tt.name, c)
+		}
+		if c != (Calibration{}) {
+			t.Errorf("%s: got calibration %+v with the error", tt.name, c)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package imu
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// EventKind is the type of crash that was detected.
+type EventKind string
+
+const (
+	// Impact is a sudden horizontal acceleration, such as hitting a wall.
+	Impact EventKind = "impact"
+
+	// Rollover is the car staying tilted past the limit, or upside down.
+	Rollover EventKind = "rollover"
+)
+
+// Event describes a detected crash.
+type Event struct {
+	Kind     EventKind `json:"kind"`
+	Time     time.Time `json:"time"`
+	Reading  Reading   `json:"reading"`
+	Attitude Attitude  `json:"attitude"`
+}
+
+// CrashDetector watches the IMU for impacts and rollovers. Once a crash has
+// been detected it stays latched until Reset is called, so that the car does
+// not start driving again by itself.
+type CrashDetector struct {
+	// ImpactThreshold is the horizontal acceleration in g that counts as an
+	// impact. The MPU6050 saturates at 2g with the default range, so this
+	// must be below that.
+	ImpactThreshold float64
+
+	// TiltThreshold is the roll or pitch in degrees past which the car is
+	// considered to be rolling over.
+	TiltThreshold float64
+
+	// TiltDuration is how long the car has to stay past the TiltThreshold,
+	// or upside down, before it counts as a rollover, so that bumps are
+	// ignored.
+	TiltDuration time.Duration
+
+	mu          sync.Mutex
+	tiltedSince time.Time
+	event       *Event
+}
+
+// NewCrashDetector returns a new CrashDetector with default thresholds.
+func NewCrashDetector() *CrashDetector {
+	return &CrashDetector{
+		ImpactThreshold: 1.5,
+		TiltThreshold:   60,
+		TiltDuration:    500 * time.Millisecond,
+	}
+}
+
+// Check looks at the latest reading and attitude. It returns the Event if a
+// new crash was detected by this call, or nil otherwise.
+func (c *CrashDetector) Check(r Reading, a Attitude) *Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.event != nil {
+		return nil
+	}
+
+	now := time.Now()
+	var kind EventKind
+
+	switch {
+	case math.Hypot(r.Accel.X, r.Accel.Y) > c.ImpactThreshold:
+		kind = Impact
+	case r.Accel.Z < 0 || math.Abs(a.Roll) > c.TiltThreshold || math.Abs(a.Pitch) > c.TiltThreshold:
+		// tilted or upside down, which a single jolt over a bump can also
+		// read as
+		if c.tiltedSince.IsZero() {
+			c.tiltedSince = now
+		}
+		if now.Sub(c.tiltedSince) < c.TiltDuration {
+			return nil
+		}
+		kind = Rollover
+	default:
+		c.tiltedSince = time.Time{}
+		return nil
+	}
+
+	c.event = &Event{Kind: kind, Time: now, Reading: r, Attitude: a}
+	return c.event
+}
+
+// Crashed returns the latched crash Event, if there is one.
+func (c *CrashDetector) Crashed() (Event, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.event == nil {
+		return Event{}, false
+	}
+	return *c.event, true
+}
+
+// Reset clears the latched crash, allowing the car to drive again.
+func (c *CrashDetector) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.event = nil
+	c.tiltedSince = time.Time{}
+}
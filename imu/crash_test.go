@@ -0,0 +1,62 @@
+package imu
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCrashDetector(t *testing.T) {
+	level := Reading{Accel: Vector{Z: 1}}
+	upsideDown := Reading{Accel: Vector{Z: -1}}
+	tilted := Attitude{Roll: 75}
+
+	tests := []struct {
+		name     string
+		readings []Reading
+		attitude Attitude
+		wait     time.Duration
+		want     EventKind
+	}{
+		{"level", []Reading{level, level}, Attitude{}, 0, ""},
+		{"impact", []Reading{{Accel: Vector{X: 1.8, Z: 1}}}, Attitude{}, 0, Impact},
+		{"one upside down jolt", []Reading{upsideDown, level}, Attitude{}, 30 * time.Millisecond, ""},
+		{"staying upside down", []Reading{upsideDown, upsideDown}, Attitude{}, 30 * time.Millisecond, Rollover},
+		{"tilted over a bump", []Reading{level}, tilted, 0, ""},
+		{"staying tilted", []Reading{level, level}, tilted, 30 * time.Millisecond, Rollover},
+	}
+	for _, tt := range tests {
+		c := NewCrashDetector()
+		c.TiltDuration = 20 * time.Millisecond
+
+		var got EventKind
+		for i, r := range tt.readings {
+			if i > 0 {
+				time.Sleep(tt.wait)
+			}
+			if e := c.Check(r, tt.attitude); e != nil {
+				got = e.Kind
+			}
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+		if _, crashed := c.Crashed(); crashed != (tt.want != "") {
+			t.Errorf("%s: Crashed() = %v", tt.name, crashed)
+		}
+	}
+}
+
+func TestCrashDetectorLatches(t *testing.T) {
+	c := NewCrashDetector()
+	impact := Reading{Accel: Vector{Y: 1.9, Z: 1}}
+	if c.Check(impact, Attitude{}) == nil {
+		t.Fatal("impact was not detected")
+	}
+	if c.Check(impact, Attitude{}) != nil {
+		t.Error("a second event was returned while latched")
+	}
+	c.Reset()
+	if _, crashed := c.Crashed(); crashed {
+		t.Error("still crashed after Reset")
+	}
+}
@@ -0,0 +1,75 @@
+package imu
+
+import "math"
+
+// Attitude is the estimated orientation of the car in degrees, along with the
+// rate it is turning around the vertical axis in degrees per second.
+//
+// The sensor is expected to be mounted with X pointing forward and Z up.
+type Attitude struct {
+	Roll    float64 `json:"roll"`
+	Pitch   float64 `json:"pitch"`
+	Yaw     float64 `json:"yaw"`
+	YawRate float64 `json:"yaw_rate"`
+}
+
+// ComplementaryFilter fuses accelerometer and gyroscope readings. The gyro
+// rates are integrated for a fast response, and roll and pitch are slowly
+// pulled towards the direction of gravity measured by the accelerometer to
+// cancel drift. The MPU6050 has no absolute heading reference, so yaw is
+// integrated from the gyro only and will drift over time.
+type ComplementaryFilter struct {
+	// Alpha is the weight given to the gyro, from 0 to 1. Values around 0.98
+	// work well at sample rates of 50-100Hz.
+	Alpha float64
+
+	attitude    Attitude
+	initialized bool
+}
+
+// NewComplementaryFilter returns a new ComplementaryFilter with the given gyro weight.
+func NewComplementaryFilter(alpha float64) *ComplementaryFilter {
+	return &ComplementaryFilter{Alpha: alpha}
+}
+
+// Reset forgets the current estimate, so that the next Update starts again
+// from the accelerometer.
+func (f *ComplementaryFilter) Reset() {
+	f.attitude = Attitude{}
+	f.initialized = false
+}
+
+// Update adds a new reading taken dt seconds after the previous one and returns
+// the new attitude estimate.
+func (f *ComplementaryFilter) Update(r Reading, dt float64) Attitude {
+	accelRoll, accelPitch := accelAngles(r.Accel)
+
+	if !f.initialized {
+		f.attitude = Attitude{Roll: accelRoll, Pitch: accelPitch}
+		f.initialized = true
+	}
+
+	f.attitude.Roll = f.Alpha*(f.attitude.Roll+r.Gyro.X*dt) + (1-f.Alpha)*accelRoll
+	f.attitude.Pitch = f.Alpha*(f.attitude.Pitch+r.Gyro.Y*dt) + (1-f.Alpha)*accelPitch
+	f.attitude.Yaw = wrapDegrees(f.attitude.Yaw + r.Gyro.Z*dt)
+	f.attitude.YawRate = r.Gyro.Z
+
+	return f.attitude
+}
+
+// accelAngles returns the roll and pitch in degrees implied by the direction
+// of gravity.
+func accelAngles(a Vector) (roll, pitch float64) {
+	roll = math.Atan2(a.Y, a.Z) * 180 / math.Pi
+	pitch = math.Atan2(-a.X, math.Sqrt(a.Y*a.Y+a.Z*a.Z)) * 180 / math.Pi
+	return
+}
+
+// wrapDegrees keeps an angle in the range -180 to 180.
+func wrapDegrees(a float64) float64 {
+	a = math.Mod(a+180, 360)
+	if a < 0 {
+		a += 360
+	}
+	return a - 180
+}
@@ -0,0 +1,108 @@
+package imu
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestAccelAngles(t *testing.T) {
+	s30, c30 := math.Sin(30*math.Pi/180), math.Cos(30*math.Pi/180)
+	tests := []struct {
+		name        string
+		accel       Vector
+		roll, pitch float64
+	}{
+		{"level", Vector{Z: 1}, 0, 0},
+		{"rolled right 30", Vector{Y: s30, Z: c30}, 30, 0},
+		{"nose up 30", Vector{X: -s30, Z: c30}, 0, 30},
+		{"on its side", Vector{Y: 1}, 90, 0},
+		{"upside down", Vector{Z: -1}, 180, 0},
+	}
+	for _, tt := range tests {
+		roll, pitch := accelAngles(tt.accel)
+		if !near(roll, tt.roll, 1e-9) || !near(pitch, tt.pitch, 1e-9) {
+			t.Errorf("%s: got roll %.3f pitch %.3f, want %.3f %.3f", tt.name, roll, pitch, tt.roll, tt.pitch)
+		}
+	}
+}
+
+func TestWrapDegrees(t *testing.T) {
+	tests := []struct{ in, want float64 }{
+		{0, 0},
+		{179, 179},
+		{181, -179},
+		{-181, 179},
+		{360, 0},
+		{-540, -180},
+		{725, 5},
+	}
+	for _, tt := range tests {
+		if got := wrapDegrees(tt.in); !near(got, tt.want, 1e-9) {
+			t.Errorf("wrapDegrees(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestComplementaryFilter(t *testing.T) {
+	s30, c30 := math.Sin(30*math.Pi/180), math.Cos(30*math.Pi/180)
+	tests := []struct {
+		name    string
+		reading Reading
+		steps   int
+		dt      float64
+		want    Attitude
+		within  float64
+	}{
+		{
+			name:    "starts from the accelerometer",
+			reading: Reading{Accel: Vector{Y: s30, Z: c30}},
+			steps:   1,
+			dt:      0.01,
+			want:    Attitude{Roll: 30},
+			within:  1e-9,
+		},
+		{
+			name:    "integrates yaw and wraps it",
+			reading: Reading{Accel: Vector{Z: 1}, Gyro: Vector{Z: 90}},
+			steps:   300,
+			dt:      0.01,
+			want:    Attitude{Yaw: -90, YawRate: 90},
+			within:  1e-6,
+		},
+		{
+			// the accelerometer bounds the drift from a gyro bias to
+			// Alpha/(1-Alpha) * bias * dt
+			name:    "cancels gyro drift in roll and pitch",
+			reading: Reading{Accel: Vector{Z: 1}, Gyro: Vector{X: 2, Y: -2}},
+			steps:   10000,
+			dt:      0.01,
+			want:    Attitude{Roll: 0.98, Pitch: -0.98, Yaw: 0},
+			within:  0.01,
+		},
+	}
+	for _, tt := range tests {
+		f := NewComplementaryFilter(0.98)
+		var a Attitude
+		for i := 0; i < tt.steps; i++ {
+			a = f.Update(tt.reading, tt.dt)
+		}
+		if !near(a.Roll, tt.want.Roll, tt.within) || !near(a.Pitch, tt.want.Pitch, tt.within) ||
+			!near(a.Yaw, tt.want.Yaw, tt.within) || !near(a.YawRate, tt.want.YawRate, tt.within) {
+			t.Errorf("%s: got %+v, want %+v", tt.name, a, tt.want)
+		}
+	}
+}
+
+func TestComplementaryFilterReset(t *testing.T) {
+	f := NewComplementaryFilter(0.98)
+	f.Update(Reading{Accel: Vector{Y: 1}, Gyro: Vector{Z: 100}}, 0.1)
+	f.Reset()
+	a := f.Update(Reading{Accel: Vector{Z: 1}}, 0.1)
+	if a != (Attitude{}) {
+		t.Errorf("after Reset got %+v, want a level attitude", a)
+	}
+}
@@ -0,0 +1,160 @@
+// Package imu turns the raw accelerometer and gyroscope data from the MPU6050
+// into an orientation estimate that the rest of the car can use.
+package imu
+
+import (
+	"sync"
+	"time"
+
+	"gobot.io/x/gobot/drivers/i2c"
+)
+
+const (
+	// AccelScale is the number of raw counts per g at the MPU6050 default +/-2g range.
+	AccelScale = 16384.0
+
+	// GyroScale is the number of raw counts per degree/second at the MPU6050
+	// default +/-250 deg/s range.
+	GyroScale = 131.0
+)
+
+// Vector is a measurement along the three axes of the sensor.
+type Vector struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	Z float64 `json:"z"`
+}
+
+// Reading is a single IMU sample. Acceleration is in g, rotation rates are in
+// degrees per second and the temperature is in degrees Celsius.
+type Reading struct {
+	Accel       Vector  `json:"accel"`
+	Gyro        Vector  `json:"gyro"`
+	Temperature float64 `json:"temperature"`
+}
+
+// Sensor is anything that can provide IMU readings.
+type Sensor interface {
+	Read() (Reading, error)
+}
+
+func (v Vector) sub(o Vector) Vector {
+	return Vector{X: v.X - o.X, Y: v.Y - o.Y, Z: v.Z - o.Z}
+}
+
+// MPU6050 is a Sensor that reads from the gobot MPU6050 driver.
+type MPU6050 struct {
+	driver *i2c.MPU6050Driver
+
+	// Calibration is applied to every reading.
+	Calibration Calibration
+}
+
+// NewMPU6050 returns a Sensor for the given MPU6050 driver that applies the
+// Calibration to each reading. Use a zero Calibration to get the raw values.
+func NewMPU6050(driver *i2c.MPU6050Driver, cal Calibration) *MPU6050 {
+	return &MPU6050{driver: driver, Calibration: cal}
+}
+
+// Read fetches the latest data from the MPU6050 and converts it to a Reading.
+func (m *MPU6050) Read() (Reading, error) {
+	if err := m.driver.GetData(); err != nil {
+		return Reading{}, err
+	}
+
+	a := m.driver.Accelerometer
+	g := m.driver.Gyroscope
+	return m.Calibration.Apply(Reading{
+		Accel:       Vector{X: float64(a.X) / AccelScale, Y: float64(a.Y) / AccelScale, Z: float64(a.Z) / AccelScale},
+		Gyro:        Vector{X: float64(g.X) / GyroScale, Y: float64(g.Y) / GyroScale, Z: float64(g.Z) / GyroScale},
+		Temperature: float64(m.driver.Temperature),
+	}), nil
+}
+
+// IMU is a car part that samples a Sensor, removes the gyro bias and fuses the
+// result into an Attitude. Call Update at the sample rate, for example from
+// gobot.Every(i.Interval, ...).
+type IMU struct {
+	Sensor   Sensor
+	Filter   *ComplementaryFilter
+	Interval time.Duration
+
+	// GyroBias is subtracted from every gyro reading, on top of any saved
+	// Calibration. The gyro bias drifts with temperature, so it is usually
+	// measured again with CalibrateGyro each time the car starts.
+	GyroBias Vector
+
+	mu       sync.RWMutex
+	reading  Reading
+	attitude Attitude
+	last     time.Time
+}
+
+// New returns a new IMU that samples the Sensor every interval.
+func New(s Sensor, interval time.Duration) *IMU {
+	return &IMU{
+		Sensor:   s,
+		Filter:   NewComplementaryFilter(0.98),
+		Interval: interval,
+	}
+}
+
+// CalibrateGyro averages the gyro over a number of samples and stores the
+// result as the GyroBias. The car must not move while this is running.
+func (i *IMU) CalibrateGyro(samples int) error {
+	var sum Vector
+	for n := 0; n < samples; n++ {
+		r, err := i.Sensor.Read()
+		if err != nil {
+			return err
+		}
+		sum.X += r.Gyro.X
+		sum.Y += r.Gyro.Y
+		sum.Z += r.Gyro.Z
+		time.Sleep(i.Interval)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.GyroBias = Vector{X: sum.X / float64(samples), Y: sum.Y / float64(samples), Z: sum.Z / float64(samples)}
+	i.Filter.Reset()
+	return nil
+}
+
+// Update takes a new sample from the Sensor and updates the attitude estimate.
+func (i *IMU) Update() error {
+	r, err := i.Sensor.Read()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	r.Gyro = r.Gyro.sub(i.GyroBias)
+
+	dt := i.Interval.Seconds()
+	if !i.last.IsZero() {
+		dt = now.Sub(i.last).Seconds()
+	}
+	i.last = now
+
+	i.reading = r
+	i.attitude = i.Filter.Update(r, dt)
+	return nil
+}
+
+// Reading returns the latest bias corrected sample.
+func (i *IMU) Reading() Reading {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.reading
+}
+
+// Attitude returns the latest orientation estimate.
+func (i *IMU) Attitude() Attitude {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.attitude
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/gophercar/runlog"
+)
+
+// Loop watches a loop that should run every Period, such as one started with
+// gobot.Every, and counts the runs that miss it: either because the run took
+// longer than the Period, or because it started late.
+type Loop struct {
+	Name   string
+	Period time.Duration
+	Timer  *Timer
+
+	// Log gets a warning, at most once a second, when a run misses the
+	// period. If it is nil the warning is only printed.
+	Log *runlog.Logger
+
+	mu       sync.Mutex
+	last     time.Time
+	missed   uint64
+	lastWarn time.Time
+}
+
+// NewLoop returns a new Loop that records how long each run takes in timer,
+// which may be nil, and warns about missed periods in log.
+func NewLoop(name string, period time.Duration, timer *Timer, log *runlog.Logger) *Loop {
+	return &Loop{Name: name, Period: period, Timer: timer, Log: log}
+}
+
+// Run calls fn, and logs a warning, at most once a second, if the loop has
+// missed its period.
+func (l *Loop) Run(fn func()) {
+	start := time.Now()
+
+	l.mu.Lock()
+	var late time.Duration
+	if !l.last.IsZero() {
+		late = start.Sub(l.last) - l.Period
+	}
+	if late < 0 {
+		late = 0
+	}
+	l.last = start
+	l.mu.Unlock()
+
+	fn()
+	elapsed := time.Since(start)
+	l.Timer.Observe(elapsed)
+
+	// allow half a period of scheduling jitter before counting a late start
+	if elapsed <= l.Period && late <= l.Period/2 {
+		return
+	}
+
+	l.mu.Lock()
+	l.missed++
+	if start.Sub(l.lastWarn) < time.Second {
+		l.mu.Unlock()
+		return
+	}
+	l.lastWarn = start
+	l.mu.Unlock()
+
+	l.Log.Warn("loop missed period", runlog.Fields{
+		"loop":    l.Name,
+		"period":  l.Period.String(),
+		"elapsed": elapsed.String(),
+		"late":    late.String(),
+		"missed":  l.Missed(),
+	})
+}
+
+// Missed returns the number of runs that have missed the period.
+func (l *Loop) Missed() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.missed
+}
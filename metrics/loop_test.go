@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hybridgroup/gophercar/runlog"
+)
+
+type buffer struct{ bytes.Buffer }
+
+func (b *buffer) Close() error { return nil }
+
+func TestLoopLogsMissedPeriods(t *testing.T) {
+	var file buffer
+	l := NewLoop("drive", 5*time.Millisecond, nil, runlog.New(&file, nil))
+
+	l.Run(func() {})
+	l.Run(func() { time.Sleep(10 * time.Millisecond) })
+	// warnings are limited to one a second
+	l.Run(func() { time.Sleep(10 * time.Millisecond) })
+
+	if got := l.Missed(); got != 2 {
+		t.Errorf("missed %d periods, want 2", got)
+	}
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("got %d log entries, want 1:\n%s", len(lines), file.String())
+	}
+	var e struct {
+		Level  string                 `json:"level"`
+		Msg    string                 `json:"msg"`
+		Fields map[string]interface{} `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Level != "warn" || e.Msg != "loop missed period" || e.Fields["loop"] != "drive" || e.Fields["missed"] != 1.0 {
+		t.Errorf("got log entry %s", lines[0])
+	}
+}
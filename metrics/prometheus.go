@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// Namespace is the prefix of all of the Prometheus metric names.
+const Namespace = "gophercar"
+
+// Types of Prometheus metrics.
+const (
+	Gauge   = "gauge"
+	Counter = "counter"
+	Summary = "summary"
+)
+
+// Writer is anything that can write its metrics in the Prometheus text format.
+type Writer interface {
+	WritePrometheus(w io.Writer)
+}
+
+// Sample is a single value of a metric, with its labels already formatted,
+// for example `axis="x"`. Labels may be empty.
+type Sample struct {
+	Labels string
+	Value  float64
+}
+
+// Label formats a label for a Sample.
+func Label(name, value string) string {
+	return fmt.Sprintf("%s=%q", name, value)
+}
+
+// WriteMetric writes the help and type lines for a metric, followed by each
+// of its samples. The Namespace is added to the front of the name.
+func WriteMetric(w io.Writer, name, typ, help string, samples ...Sample) {
+	name = Namespace + "_" + name
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, typ)
+	for _, s := range samples {
+		if s.Labels == "" {
+			fmt.Fprintf(w, "%s %g\n", name, s.Value)
+		} else {
+			fmt.Fprintf(w, "%s{%s} %g\n", name, s.Labels, s.Value)
+		}
+	}
+}
+
+// WriteValue writes a metric that has a single sample with no labels.
+func WriteValue(w io.Writer, name, typ, help string, value float64) {
+	WriteMetric(w, name, typ, help, Sample{Value: value})
+}
+
+// quantiles are the percentiles reported for each stage.
+var quantiles = []struct {
+	label string
+	value func(Stats) float64
+}{
+	{"0.5", func(s Stats) float64 { return s.P50MS }},
+	{"0.9", func(s Stats) float64 { return s.P90MS }},
+	{"0.99", func(s Stats) float64 { return s.P99MS }},
+}
+
+// WritePrometheus writes the stage timings as a Prometheus summary, in seconds.
+func (t *Timings) WritePrometheus(w io.Writer) {
+	var samples []Sample
+	for _, stage := range t.Names() {
+		s := t.Timer(stage).Stats()
+		for _, q := range quantiles {
+			samples = append(samples, Sample{Label("stage", stage) + "," + Label("quantile", q.label), q.value(s) / 1000})
+		}
+	}
+	WriteMetric(w, "stage_duration_seconds", Summary, "Time taken by each stage of the car.", samples...)
+
+	// the sum and count lines belong to the summary above
+	name := Namespace + "_stage_duration_seconds"
+	for _, stage := range t.Names() {
+		s := t.Timer(stage).Stats()
+		fmt.Fprintf(w, "%s_sum{stage=%q} %g\n", name, stage, s.SumMS/1000)
+		fmt.Fprintf(w, "%s_count{stage=%q} %d\n", name, stage, s.Count)
+	}
+}
+
+// Loops is a set of Loops that are written to Prometheus together.
+type Loops []*Loop
+
+// WritePrometheus writes the number of missed periods of each Loop as a
+// Prometheus counter.
+func (loops Loops) WritePrometheus(w io.Writer) {
+	var samples []Sample
+	for _, l := range loops {
+		samples = append(samples, Sample{Label("loop", l.Name), float64(l.Missed())})
+	}
+	WriteMetric(w, "loop_missed_total", Counter, "Number of times a loop missed its period.", samples...)
+}
+
+// Handler returns an http.Handler that serves the metrics of all of the
+// writers in the Prometheus text format, for example at /metrics.
+func Handler(writers ...Writer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, writer := range writers {
+			writer.WritePrometheus(w)
+		}
+	})
+}
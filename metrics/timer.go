@@ -0,0 +1,193 @@
+// Package metrics measures how long each stage of the car takes, from reading
+// a camera frame to setting the servo, and makes the results available as JSON
+// or in the Prometheus text format.
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultWindow is the number of recent samples the percentiles are worked out from.
+const DefaultWindow = 1000
+
+// Names of the stages timed by the cars.
+const (
+	// StageCapture is reading a frame from the camera.
+	StageCapture = "capture"
+	// StageVision is finding the line in a frame.
+	StageVision = "vision"
+	// StageDetect is finding objects in a frame.
+	StageDetect = "detect"
+	// StageControl is working out the steering and throttle.
+	StageControl = "control"
+	// StageActuation is sending the steering and throttle to the PCA9685.
+	StageActuation = "actuation"
+	// StageEncoding is encoding a frame for the MJPEG stream.
+	StageEncoding = "encoding"
+	// StageLatency is the whole way from a frame being read to the steering
+	// worked out from it being sent to the PCA9685.
+	StageLatency = "latency"
+	// StageDrive is a whole run of the drive loop.
+	StageDrive = "drive"
+)
+
+// Stats is a summary of the samples in a Timer. Count and SumMS cover all
+// samples ever observed, the rest only the recent window.
+type Stats struct {
+	Count  uint64  `json:"count"`
+	SumMS  float64 `json:"sum_ms"`
+	LastMS float64 `json:"last_ms"`
+	MeanMS float64 `json:"mean_ms"`
+	P50MS  float64 `json:"p50_ms"`
+	P90MS  float64 `json:"p90_ms"`
+	P99MS  float64 `json:"p99_ms"`
+	MaxMS  float64 `json:"max_ms"`
+}
+
+// Timer keeps a rolling window of durations. A nil *Timer ignores everything
+// it is given, so timing can be optional.
+type Timer struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+	count   uint64
+	sum     time.Duration
+	last    time.Duration
+}
+
+// NewTimer returns a Timer that works out percentiles from the last window samples.
+func NewTimer(window int) *Timer {
+	return &Timer{samples: make([]time.Duration, window)}
+}
+
+// Observe adds a new duration.
+func (t *Timer) Observe(d time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.samples[t.next] = d
+	t.next = (t.next + 1) % len(t.samples)
+	if t.next == 0 {
+		t.full = true
+	}
+	t.count++
+	t.sum += d
+	t.last = d
+}
+
+// Since adds the time elapsed since start.
+func (t *Timer) Since(start time.Time) {
+	t.Observe(time.Since(start))
+}
+
+// Stats returns a summary of the samples.
+func (t *Timer) Stats() Stats {
+	if t == nil {
+		return Stats{}
+	}
+
+	t.mu.Lock()
+	n := t.next
+	if t.full {
+		n = len(t.samples)
+	}
+	window := make([]time.Duration, n)
+	copy(window, t.samples[:n])
+	s := Stats{Count: t.count, SumMS: ms(t.sum), LastMS: ms(t.last)}
+	t.mu.Unlock()
+
+	if n == 0 {
+		return s
+	}
+
+	sort.Slice(window, func(i, j int) bool { return window[i] < window[j] })
+	var total time.Duration
+	for _, d := range window {
+		total += d
+	}
+	s.MeanMS = ms(total / time.Duration(n))
+	s.P50MS = ms(percentile(window, 0.5))
+	s.P90MS = ms(percentile(window, 0.9))
+	s.P99MS = ms(percentile(window, 0.99))
+	s.MaxMS = ms(window[n-1])
+	return s
+}
+
+// percentile returns the p percentile, from 0 to 1, of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	i := int(p*float64(len(sorted))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(sorted) {
+		i = len(sorted) - 1
+	}
+	return sorted[i]
+}
+
+func ms(d time.Duration) float64 {
+	return d.Seconds() * 1000
+}
+
+// Timings is a named set of Timers, one for each stage of the car. A nil
+// *Timings returns nil Timers.
+type Timings struct {
+	mu     sync.Mutex
+	window int
+	timers map[string]*Timer
+	names  []string
+}
+
+// NewTimings returns a new Timings whose Timers keep window samples.
+func NewTimings(window int) *Timings {
+	return &Timings{window: window, timers: make(map[string]*Timer)}
+}
+
+// Timer returns the Timer for the named stage, creating it if needed.
+func (t *Timings) Timer(name string) *Timer {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timer, ok := t.timers[name]
+	if !ok {
+		timer = NewTimer(t.window)
+		t.timers[name] = timer
+		t.names = append(t.names, name)
+	}
+	return timer
+}
+
+// Names returns the names of all of the stages, in the order they were added.
+func (t *Timings) Names() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.names...)
+}
+
+// Stats returns the summary for every stage.
+func (t *Timings) Stats() map[string]Stats {
+	stats := make(map[string]Stats)
+	for _, name := range t.Names() {
+		stats[name] = t.Timer(name).Stats()
+	}
+	return stats
+}
+
+// ServeHTTP writes the summary for every stage as JSON.
+func (t *Timings) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.Stats())
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"image"
+	"image/color"
+)
+
+// Input is how camera images are turned into the input of a Model. The top
+// of the image is cropped off, the same as the line follower does, and the
+// rest is turned grey and shrunk to Width by Height pixels, with levels from
+// 0 to 1.
+type Input struct {
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	CropTop float64 `json:"crop_top"`
+}
+
+// Size returns the number of values in an input.
+func (in Input) Size() int {
+	return in.Width * in.Height
+}
+
+// FromImage returns the input for img, such as a JPEG from a tub.
+func (in Input) FromImage(img image.Image) []float32 {
+	b := img.Bounds()
+	if ycc, ok := img.(*image.YCbCr); ok {
+		// the luma of a JPEG is already the grey level
+		return in.resize(b.Dx(), b.Dy(), func(x, y int) uint8 {
+			return ycc.Y[ycc.YOffset(b.Min.X+x, b.Min.Y+y)]
+		})
+	}
+	return in.resize(b.Dx(), b.Dy(), func(x, y int) uint8 {
+		return color.GrayModel.Convert(img.At(b.Min.X+x, b.Min.Y+y)).(color.Gray).Y
+	})
+}
+
+// FromBGR returns the input for a w by h image of BGR pixels, such as the
+// bytes of a camera frame.
+func (in Input) FromBGR(pix []byte, w, h int) []float32 {
+	return in.resize(w, h, func(x, y int) uint8 {
+		i := 3 * (y*w + x)
+		// the same weights as OpenCV and color.GrayModel
+		return uint8((19595*uint32(pix[i+2]) + 38470*uint32(pix[i+1]) + 7471*uint32(pix[i]) + 1<<15) >> 16)
+	})
+}
+
+// resize crops and shrinks a w by h image, averaging the pixels that fall in
+// each input pixel.
+func (in Input) resize(w, h int, gray func(x, y int) uint8) []float32 {
+	top := int(float64(h) * in.CropTop)
+	h -= top
+	out := make([]float32, in.Size())
+	for oy := 0; oy < in.Height; oy++ {
+		y0, y1 := span(oy, in.Height, h)
+		for ox := 0; ox < in.Width; ox++ {
+			x0, x1 := span(ox, in.Width, w)
+			var sum uint32
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					sum += uint32(gray(x, top+y))
+				}
+			}
+			out[oy*in.Width+ox] = float32(sum) / float32((y1-y0)*(x1-x0)) / 255
+		}
+	}
+	return out
+}
+
+// span returns the range of the n source pixels that fall in output pixel i
+// of m, which always has at least one pixel.
+func span(i, m, n int) (int, int) {
+	a, b := i*n/m, (i+1)*n/m
+	if b <= a {
+		b = a + 1
+	}
+	return a, b
+}
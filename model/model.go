@@ -0,0 +1,250 @@
+// Package model is a small convolutional neural network that learns to drive
+// from recorded tubs, the same way as the Donkeycar linear model. It is
+// written in plain Go, so it trains on a laptop CPU and runs on the Pi
+// without any other libraries.
+//
+// A Model takes a camera image, made into an input as described by Input,
+// through a stack of convolutions and fully connected layers to the steering
+// and the throttle.
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+)
+
+// Version is the version of the model file format.
+const Version = 1
+
+// Conv is a convolution layer followed by a ReLU, with Out filters of Size by
+// Size pixels over In channels, moved Stride pixels at a time.
+type Conv struct {
+	In     int       `json:"in"`
+	Out    int       `json:"out"`
+	Size   int       `json:"size"`
+	Stride int       `json:"stride"`
+	W      []float32 `json:"w"`
+	B      []float32 `json:"b"`
+}
+
+// Dense is a fully connected layer. It is followed by a ReLU, except for the
+// last layer of a Model.
+type Dense struct {
+	In  int       `json:"in"`
+	Out int       `json:"out"`
+	W   []float32 `json:"w"`
+	B   []float32 `json:"b"`
+}
+
+// Model is a trained pilot.
+type Model struct {
+	Version int      `json:"version"`
+	Input   Input    `json:"input"`
+	Conv    []*Conv  `json:"conv"`
+	Dense   []*Dense `json:"dense"`
+
+	// Epochs is the number of epochs it was trained for, and Loss its mean
+	// squared error on the validation records.
+	Epochs int     `json:"epochs"`
+	Loss   float64 `json:"loss"`
+
+	state *state
+}
+
+// New returns an untrained Model for inputs made as in describes, with weights
+// initialised from the seed. It has two convolutions and a hidden layer of
+// 32, and outputs the steering and the throttle.
+func New(in Input, seed int64) *Model {
+	rnd := rand.New(rand.NewSource(seed))
+	m := &Model{Version: Version, Input: in}
+
+	c, w, h := 1, in.Width, in.Height
+	for _, l := range []struct{ out, size, stride int }{{8, 5, 2}, {16, 3, 2}} {
+		conv := &Conv{In: c, Out: l.out, Size: l.size, Stride: l.stride}
+		conv.W = initWeights(rnd, l.out*c*l.size*l.size, c*l.size*l.size)
+		conv.B = make([]float32, l.out)
+		m.Conv = append(m.Conv, conv)
+		c, w, h = l.out, conv.outSize(w), conv.outSize(h)
+	}
+
+	n := c * w * h
+	for _, out := range []int{32, 2} {
+		d := &Dense{In: n, Out: out, W: initWeights(rnd, out*n, n), B: make([]float32, out)}
+		m.Dense = append(m.Dense, d)
+		n = out
+	}
+	return m
+}
+
+// initWeights returns n random weights scaled for a ReLU with fanIn inputs.
+func initWeights(rnd *rand.Rand, n, fanIn int) []float32 {
+	w := make([]float32, n)
+	scale := math.Sqrt(2 / float64(fanIn))
+	for i := range w {
+		w[i] = float32(rnd.NormFloat64() * scale)
+	}
+	return w
+}
+
+func (c *Conv) outSize(n int) int {
+	return (n-c.Size)/c.Stride + 1
+}
+
+// Load reads a model saved with Save.
+func Load(path string) (*Model, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &Model{}
+	if err := json.NewDecoder(f).Decode(m); err != nil {
+		return nil, fmt.Errorf("reading model %s: %v", path, err)
+	}
+	if m.Version != Version {
+		return nil, fmt.Errorf("model %s is version %d, not %d", path, m.Version, Version)
+	}
+	if err := m.check(); err != nil {
+		return nil, fmt.Errorf("model %s: %v", path, err)
+	}
+	return m, nil
+}
+
+// Save writes the model to path.
+func (m *Model) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(m); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// check makes sure the layers fit together.
+func (m *Model) check() error {
+	c, w, h := 1, m.Input.Width, m.Input.Height
+	for i, conv := range m.Conv {
+		if conv.In != c || len(conv.W) != conv.Out*conv.In*conv.Size*conv.Size || len(conv.B) != conv.Out || conv.Stride < 1 {
+			return fmt.Errorf("convolution %d does not fit", i)
+		}
+		c, w, h = conv.Out, conv.outSize(w), conv.outSize(h)
+		if w < 1 || h < 1 {
+			return fmt.Errorf("convolution %d is bigger than its input", i)
+		}
+	}
+	n := c * w * h
+	for i, d := range m.Dense {
+		if d.In != n || len(d.W) != d.Out*d.In || len(d.B) != d.Out {
+			return fmt.Errorf("layer %d does not fit", i)
+		}
+		n = d.Out
+	}
+	if n != 2 {
+		return fmt.Errorf("has %d outputs, not 2", n)
+	}
+	return nil
+}
+
+// Predict returns the steering and throttle for an input. It is not safe to
+// call from more than one goroutine at a time.
+func (m *Model) Predict(input []float32) (steering, throttle float64) {
+	if m.state == nil {
+		m.state = newState(m)
+	}
+	out := m.state.forward(m, input)
+	return float64(out[0]), float64(out[1])
+}
+
+// state holds the activations of each layer for one input, and the gradients
+// while training.
+type state struct {
+	// acts[0] is the input, and acts[i+1] the output of layer i, the
+	// convolutions first.
+	acts  [][]float32
+	dims  [][3]int
+	grads [][]float32
+}
+
+func newState(m *Model) *state {
+	s := &state{}
+	c, w, h := 1, m.Input.Width, m.Input.Height
+	s.dims = append(s.dims, [3]int{c, h, w})
+	for _, conv := range m.Conv {
+		c, w, h = conv.Out, conv.outSize(w), conv.outSize(h)
+		s.dims = append(s.dims, [3]int{c, h, w})
+	}
+	for _, d := range m.Dense {
+		s.dims = append(s.dims, [3]int{d.Out, 1, 1})
+	}
+	for _, d := range s.dims {
+		s.acts = append(s.acts, make([]float32, d[0]*d[1]*d[2]))
+		s.grads = append(s.grads, make([]float32, d[0]*d[1]*d[2]))
+	}
+	return s
+}
+
+// forward runs the layers over the input and returns the output.
+func (s *state) forward(m *Model, input []float32) []float32 {
+	copy(s.acts[0], input)
+	l := 0
+	for _, conv := range m.Conv {
+		conv.forward(s.acts[l], s.dims[l], s.acts[l+1], s.dims[l+1])
+		l++
+	}
+	for i, d := range m.Dense {
+		d.forward(s.acts[l], s.acts[l+1], i < len(m.Dense)-1)
+		l++
+	}
+	return s.acts[l]
+}
+
+func (c *Conv) forward(in []float32, inDim [3]int, out []float32, outDim [3]int) {
+	inH, inW := inDim[1], inDim[2]
+	outH, outW := outDim[1], outDim[2]
+	k := c.Size
+	for o := 0; o < c.Out; o++ {
+		w := c.W[o*c.In*k*k : (o+1)*c.In*k*k]
+		for y := 0; y < outH; y++ {
+			for x := 0; x < outW; x++ {
+				sum := c.B[o]
+				for i := 0; i < c.In; i++ {
+					for ky := 0; ky < k; ky++ {
+						row := in[(i*inH+y*c.Stride+ky)*inW+x*c.Stride:]
+						wrow := w[(i*k+ky)*k : (i*k+ky+1)*k]
+						for kx, wv := range wrow {
+							sum += wv * row[kx]
+						}
+					}
+				}
+				out[(o*outH+y)*outW+x] = relu(sum)
+			}
+		}
+	}
+}
+
+func (d *Dense) forward(in, out []float32, activate bool) {
+	for o := 0; o < d.Out; o++ {
+		sum := d.B[o]
+		for i, wv := range d.W[o*d.In : (o+1)*d.In] {
+			sum += wv * in[i]
+		}
+		if activate {
+			sum = relu(sum)
+		}
+		out[o] = sum
+	}
+}
+
+func relu(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,194 @@
+package model
+
+import (
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testInput = Input{Width: 16, Height: 12, CropTop: 0.4}
+
+func randomInput(rnd *rand.Rand, in Input) []float32 {
+	input := make([]float32, in.Size())
+	for i := range input {
+		input[i] = rnd.Float32()
+	}
+	return input
+}
+
+// TestBackward compares the gradient worked out by backward with one found
+// by nudging each weight and bias in turn and measuring the loss.
+func TestBackward(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	m := New(testInput, 1)
+	// give the biases a value, so that their gradients are checked with
+	// the ReLUs in a realistic state
+	var biases [][]float32
+	for _, conv := range m.Conv {
+		biases = append(biases, conv.B)
+	}
+	for _, d := range m.Dense {
+		biases = append(biases, d.B)
+	}
+	for _, b := range biases {
+		for i := range b {
+			b[i] = rnd.Float32()*0.2 - 0.1
+		}
+	}
+	input := randomInput(rnd, testInput)
+	const steering, throttle = 0.3, 0.6
+
+	s := newState(m)
+	loss := func() float64 {
+		out := s.forward(m, input)
+		ds, dt := float64(out[0])-steering, float64(out[1])-throttle
+		return (ds*ds + dt*dt) / 2
+	}
+
+	out := s.forward(m, input)
+	grads := zeros(m.params())
+	s.backward(m, []float32{out[0] - steering, out[1] - throttle}, grads)
+
+	// small enough that hardly any ReLU is pushed past its kink, and big
+	// enough for the float32 loss to still resolve the change
+	const eps = 1e-3
+	checked, bad := 0, 0
+	for i, p := range m.params() {
+		for j := range p {
+			v := p[j]
+			p[j] = v + eps
+			up := loss()
+			p[j] = v - eps
+			down := loss()
+			p[j] = v
+
+			numeric := (up - down) / (2 * eps)
+			analytic := float64(grads[i][j])
+			checked++
+			if math.Abs(numeric-analytic) > 1e-3+0.05*math.Max(math.Abs(numeric), math.Abs(analytic)) {
+				bad++
+				if bad <= 10 {
+					t.Errorf("parameter %d[%d]: backward gives %.5f, the loss changes by %.5f", i, j, analytic, numeric)
+				}
+			}
+		}
+	}
+	if bad > 0 {
+		t.Errorf("%d of %d gradients are wrong", bad, checked)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := New(testInput, 2)
+	m.Epochs = 12
+	m.Loss = 0.034
+	path := filepath.Join(dir, "model.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(loaded.Input, m.Input) || loaded.Epochs != m.Epochs || loaded.Loss != m.Loss {
+		t.Errorf("loaded %+v %d %v, saved %+v %d %v", loaded.Input, loaded.Epochs, loaded.Loss, m.Input, m.Epochs, m.Loss)
+	}
+	if !reflect.DeepEqual(loaded.params(), m.params()) {
+		t.Error("the weights changed")
+	}
+
+	input := randomInput(rand.New(rand.NewSource(2)), testInput)
+	s1, t1 := m.Predict(input)
+	s2, t2 := loaded.Predict(input)
+	if s1 != s2 || t1 != t2 {
+		t.Errorf("loaded model predicts %v %v, saved one %v %v", s2, t2, s1, t1)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(m *Model)
+		err    string
+	}{
+		{"as made", func(m *Model) {}, ""},
+		{"conv weights missing", func(m *Model) { m.Conv[0].W = m.Conv[0].W[1:] }, "convolution 0 does not fit"},
+		{"conv channels", func(m *Model) { m.Conv[1].In = 4 }, "convolution 1 does not fit"},
+		{"conv stride", func(m *Model) { m.Conv[0].Stride = 0 }, "convolution 0 does not fit"},
+		{"input too small", func(m *Model) { m.Input.Width = 6 }, "convolution 1 is bigger than its input"},
+		{"input size", func(m *Model) { m.Input.Width = 24 }, "layer 0 does not fit"},
+		{"dense bias", func(m *Model) { m.Dense[1].B = nil }, "layer 1 does not fit"},
+		{"outputs", func(m *Model) { m.Dense = m.Dense[:1] }, "has 32 outputs, not 2"},
+	}
+	for _, tt := range tests {
+		m := New(testInput, 3)
+		tt.change(m)
+		err := m.check()
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s: got error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestLoadRejects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := New(testInput, 4)
+	m.Dense[0].In++
+	path := filepath.Join(dir, "model.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("loaded a model whose layers do not fit")
+	}
+
+	m = New(testInput, 4)
+	m.Version = Version + 1
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("loaded a model of another version")
+	}
+}
+
+// TestEpochBatchSize checks that a batch size below 1 trains on one sample
+// at a time, rather than never getting through the samples.
+func TestEpochBatchSize(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	samples := make([]Sample, 3)
+	for i := range samples {
+		samples[i] = Sample{Input: randomInput(rnd, testInput), Steering: 0.1, Throttle: 0.5}
+	}
+
+	for _, size := range []int{0, -1} {
+		tr := NewTrainer(New(testInput, 1), 1)
+		tr.BatchSize = size
+		if loss := tr.Epoch(samples); math.IsNaN(loss) || loss <= 0 {
+			t.Errorf("batch size %d: loss %g", size, loss)
+		}
+		if tr.step != len(samples) {
+			t.Errorf("batch size %d: took %d steps over %d samples", size, tr.step, len(samples))
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// Sample is an input with the steering and throttle the driver used.
+type Sample struct {
+	Input    []float32
+	Steering float32
+	Throttle float32
+}
+
+// Trainer trains a Model with the Adam optimiser, to minimise the mean
+// squared error of the steering and throttle.
+type Trainer struct {
+	Model        *Model
+	LearningRate float64
+
+	// BatchSize is the number of samples in each step, and is taken as 1 if
+	// it is less.
+	BatchSize int
+
+	// Workers is the number of goroutines that share each batch.
+	Workers int
+
+	rnd    *rand.Rand
+	step   int
+	mean   [][]float32
+	square [][]float32
+	states []*state
+	grads  [][][]float32
+}
+
+// NewTrainer returns a Trainer for m, that shuffles the samples with seed.
+func NewTrainer(m *Model, seed int64) *Trainer {
+	t := &Trainer{
+		Model:        m,
+		LearningRate: 0.001,
+		BatchSize:    64,
+		Workers:      runtime.NumCPU(),
+		rnd:          rand.New(rand.NewSource(seed)),
+	}
+	t.mean = zeros(m.params())
+	t.square = zeros(m.params())
+	return t
+}
+
+// Epoch trains on each of the samples once, in a random order, and returns
+// the mean loss over them.
+func (t *Trainer) Epoch(samples []Sample) float64 {
+	for len(t.states) < t.Workers {
+		t.states = append(t.states, newState(t.Model))
+		t.grads = append(t.grads, zeros(t.Model.params()))
+	}
+
+	size := t.BatchSize
+	if size < 1 {
+		size = 1
+	}
+
+	order := t.rnd.Perm(len(samples))
+	loss := 0.0
+	for start := 0; start < len(order); start += size {
+		end := start + size
+		if end > len(order) {
+			end = len(order)
+		}
+		loss += t.batch(samples, order[start:end])
+	}
+	return loss / float64(len(samples))
+}
+
+// batch works out the gradient over a batch of samples, shared out between
+// the workers, and takes an Adam step. It returns the total loss.
+func (t *Trainer) batch(samples []Sample, batch []int) float64 {
+	workers := t.Workers
+	if workers > len(batch) {
+		workers = len(batch)
+	}
+	losses := make([]float64, workers)
+	scale := 1 / float32(len(batch))
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			s, g := t.states[w], t.grads[w]
+			for _, p := range g {
+				fill(p, 0)
+			}
+			for i := w; i < len(batch); i += workers {
+				sample := samples[batch[i]]
+				out := s.forward(t.Model, sample.Input)
+				ds, dt := out[0]-sample.Steering, out[1]-sample.Throttle
+				losses[w] += float64(ds*ds+dt*dt) / 2
+				s.backward(t.Model, []float32{ds * scale, dt * scale}, g)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	// sum the gradients into the first worker's
+	for w := 1; w < workers; w++ {
+		for i, p := range t.grads[w] {
+			sum := t.grads[0][i]
+			for j, v := range p {
+				sum[j] += v
+			}
+		}
+	}
+	t.adam(t.grads[0])
+
+	loss := 0.0
+	for _, l := range losses {
+		loss += l
+	}
+	return loss
+}
+
+// adam moves the weights against the gradient.
+func (t *Trainer) adam(grads [][]float32) {
+	const beta1, beta2, epsilon = 0.9, 0.999, 1e-8
+	t.step++
+	lr := t.LearningRate * math.Sqrt(1-math.Pow(beta2, float64(t.step))) / (1 - math.Pow(beta1, float64(t.step)))
+	for i, p := range t.Model.params() {
+		g, mean, square := grads[i], t.mean[i], t.square[i]
+		for j := range p {
+			mean[j] = beta1*mean[j] + (1-beta1)*g[j]
+			square[j] = beta2*square[j] + (1-beta2)*g[j]*g[j]
+			p[j] -= float32(lr * float64(mean[j]) / (math.Sqrt(float64(square[j])) + epsilon))
+		}
+	}
+}
+
+// Evaluate returns the mean loss of the model over the samples.
+func (m *Model) Evaluate(samples []Sample) float64 {
+	loss := 0.0
+	for _, sample := range samples {
+		steering, throttle := m.Predict(sample.Input)
+		ds, dt := steering-float64(sample.Steering), throttle-float64(sample.Throttle)
+		loss += (ds*ds + dt*dt) / 2
+	}
+	return loss / float64(len(samples))
+}
+
+// Clone returns a copy of the model, such as to keep the best one found
+// while training.
+func (m *Model) Clone() *Model {
+	c := &Model{Version: m.Version, Input: m.Input, Epochs: m.Epochs, Loss: m.Loss}
+	for _, conv := range m.Conv {
+		cc := *conv
+		cc.W = append([]float32(nil), conv.W...)
+		cc.B = append([]float32(nil), conv.B...)
+		c.Conv = append(c.Conv, &cc)
+	}
+	for _, d := range m.Dense {
+		dc := *d
+		dc.W = append([]float32(nil), d.W...)
+		dc.B = append([]float32(nil), d.B...)
+		c.Dense = append(c.Dense, &dc)
+	}
+	return c
+}
+
+// params returns the weights and biases of each layer, in order.
+func (m *Model) params() [][]float32 {
+	var p [][]float32
+	for _, conv := range m.Conv {
+		p = append(p, conv.W, conv.B)
+	}
+	for _, d := range m.Dense {
+		p = append(p, d.W, d.B)
+	}
+	return p
+}
+
+// backward adds the gradient of the loss for the last input given to forward
+// to grads, from the gradient dOut of the output.
+func (s *state) backward(m *Model, dOut []float32, grads [][]float32) {
+	l := len(s.acts) - 1
+	copy(s.grads[l], dOut)
+	g := len(grads)
+	for i := len(m.Dense) - 1; i >= 0; i-- {
+		g -= 2
+		d := m.Dense[i]
+		if i < len(m.Dense)-1 {
+			reluGrad(s.grads[l], s.acts[l])
+		}
+		d.backward(s.acts[l-1], s.grads[l], s.grads[l-1], grads[g], grads[g+1], l > 1)
+		l--
+	}
+	for i := len(m.Conv) - 1; i >= 0; i-- {
+		g -= 2
+		conv := m.Conv[i]
+		reluGrad(s.grads[l], s.acts[l])
+		conv.backward(s.acts[l-1], s.dims[l-1], s.grads[l], s.dims[l], s.grads[l-1], grads[g], grads[g+1], l > 1)
+		l--
+	}
+}
+
+// reluGrad zeroes the gradient where the ReLU was not active.
+func reluGrad(grad, act []float32) {
+	for i, a := range act {
+		if a <= 0 {
+			grad[i] = 0
+		}
+	}
+}
+
+func (d *Dense) backward(in, dOut, dIn, gW, gB []float32, propagate bool) {
+	if propagate {
+		fill(dIn, 0)
+	}
+	for o, do := range dOut {
+		if do == 0 {
+			continue
+		}
+		gB[o] += do
+		w := d.W[o*d.In : (o+1)*d.In]
+		gw := gW[o*d.In : (o+1)*d.In]
+		for i, v := range in {
+			gw[i] += do * v
+		}
+		if propagate {
+			for i, wv := range w {
+				dIn[i] += do * wv
+			}
+		}
+	}
+}
+
+func (c *Conv) backward(in []float32, inDim [3]int, dOut []float32, outDim [3]int, dIn, gW, gB []float32, propagate bool) {
+	inH, inW := inDim[1], inDim[2]
+	outH, outW := outDim[1], outDim[2]
+	k := c.Size
+	if propagate {
+		fill(dIn, 0)
+	}
+	for o := 0; o < c.Out; o++ {
+		w := c.W[o*c.In*k*k : (o+1)*c.In*k*k]
+		gw := gW[o*c.In*k*k : (o+1)*c.In*k*k]
+		for y := 0; y < outH; y++ {
+			for x := 0; x < outW; x++ {
+				do := dOut[(o*outH+y)*outW+x]
+				if do == 0 {
+					continue
+				}
+				gB[o] += do
+				for i := 0; i < c.In; i++ {
+					for ky := 0; ky < k; ky++ {
+						at := (i*inH+y*c.Stride+ky)*inW + x*c.Stride
+						wi := (i*k + ky) * k
+						for kx := 0; kx < k; kx++ {
+							gw[wi+kx] += do * in[at+kx]
+							if propagate {
+								dIn[at+kx] += do * w[wi+kx]
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func zeros(p [][]float32) [][]float32 {
+	z := make([][]float32, len(p))
+	for i := range p {
+		z[i] = make([]float32, len(p[i]))
+	}
+	return z
+}
+
+func fill(p []float32, v float32) {
+	for i := range p {
+		p[i] = v
+	}
+}
@@ -0,0 +1,111 @@
+// Package odometry measures how fast and how far the car goes, from the pulses
+// of a hall effect sensor or an optical encoder on a wheel or the motor, so
+// that the speed is known whatever the battery voltage.
+package odometry
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gobot.io/x/gobot"
+)
+
+// Counter counts the pulses of an encoder.
+type Counter interface {
+	Count() uint64
+}
+
+// Reader reads GPIO pins, such as the raspi.Adaptor.
+type Reader interface {
+	DigitalRead(pin string) (int, error)
+}
+
+// EncoderDriver counts the rising edges on a GPIO pin, such as from a hall
+// effect sensor and magnets on a wheel. gobot has no GPIO interrupts on the
+// Pi, so the pin is polled every PollInterval, which limits the pulse rate it
+// can count to half of the poll rate.
+type EncoderDriver struct {
+	Pin          string
+	PollInterval time.Duration
+
+	name   string
+	pins   Reader
+	count  uint64
+	errors uint64
+	halt   chan struct{}
+	done   sync.WaitGroup
+}
+
+// NewEncoderDriver returns a driver for an encoder on pin of the adaptor.
+func NewEncoderDriver(a Reader, pin string) *EncoderDriver {
+	return &EncoderDriver{
+		Pin:          pin,
+		PollInterval: 500 * time.Microsecond,
+		name:         gobot.DefaultName("Encoder"),
+		pins:         a,
+	}
+}
+
+// Name returns the name of the driver.
+func (e *EncoderDriver) Name() string { return e.name }
+
+// SetName sets the name of the driver.
+func (e *EncoderDriver) SetName(n string) { e.name = n }
+
+// Connection returns the adaptor of the driver.
+func (e *EncoderDriver) Connection() gobot.Connection { return e.pins.(gobot.Connection) }
+
+// Start reads the pin once, to check that it works, and starts counting.
+func (e *EncoderDriver) Start() error {
+	level, err := e.pins.DigitalRead(e.Pin)
+	if err != nil {
+		return err
+	}
+	e.halt = make(chan struct{})
+	e.done.Add(1)
+	go e.poll(level)
+	return nil
+}
+
+// Halt stops counting.
+func (e *EncoderDriver) Halt() error {
+	if e.halt != nil {
+		close(e.halt)
+		e.done.Wait()
+		e.halt = nil
+	}
+	return nil
+}
+
+// Count returns the number of pulses counted since the driver started.
+func (e *EncoderDriver) Count() uint64 {
+	return atomic.LoadUint64(&e.count)
+}
+
+// Errors returns the number of times reading the pin failed.
+func (e *EncoderDriver) Errors() uint64 {
+	return atomic.LoadUint64(&e.errors)
+}
+
+func (e *EncoderDriver) poll(last int) {
+	defer e.done.Done()
+	tick := time.NewTicker(e.PollInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-e.halt:
+			return
+		case <-tick.C:
+		}
+		level, err := e.pins.DigitalRead(e.Pin)
+		if err != nil {
+			atomic.AddUint64(&e.errors, 1)
+			continue
+		}
+		if level == 1 && last == 0 {
+			atomic.AddUint64(&e.count, 1)
+		}
+		last = level
+	}
+}
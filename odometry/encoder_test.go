@@ -0,0 +1,89 @@
+package odometry
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errRead = errors.New("read failed")
+
+// fakePins reads the levels in turn, with -1 for a failed read, and then
+// keeps reading the last level. done is closed once they have all been read.
+type fakePins struct {
+	mu     sync.Mutex
+	levels []int
+	reads  int
+	done   chan struct{}
+}
+
+func newFakePins(levels ...int) *fakePins {
+	return &fakePins{levels: levels, done: make(chan struct{})}
+}
+
+func (p *fakePins) DigitalRead(pin string) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reads++
+	if p.reads == len(p.levels) {
+		close(p.done)
+	}
+	level := p.levels[len(p.levels)-1]
+	if p.reads <= len(p.levels) {
+		level = p.levels[p.reads-1]
+	}
+	if level < 0 {
+		return 0, errRead
+	}
+	return level, nil
+}
+
+func (p *fakePins) Reads() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.reads
+}
+
+func TestEncoderDriver(t *testing.T) {
+	// starting high does not count; the read after a failure is compared
+	// with the level before it
+	pins := newFakePins(1, 1, 0, 1, 1, 0, -1, 1, 0, 0, 1, 1)
+	e := NewEncoderDriver(pins, "7")
+	e.PollInterval = 100 * time.Microsecond
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-pins.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("only %d reads", pins.Reads())
+	}
+	if err := e.Halt(); err != nil {
+		t.Fatal(err)
+	}
+	if e.Count() != 3 || e.Errors() != 1 {
+		t.Errorf("counted %d pulses and %d errors, want 3 and 1", e.Count(), e.Errors())
+	}
+
+	// no more reads once halted
+	reads := pins.Reads()
+	time.Sleep(10 * e.PollInterval)
+	if pins.Reads() != reads {
+		t.Errorf("read %d more times after Halt", pins.Reads()-reads)
+	}
+	if err := e.Halt(); err != nil {
+		t.Errorf("a second Halt returned %v", err)
+	}
+}
+
+func TestEncoderDriverStart(t *testing.T) {
+	e := NewEncoderDriver(newFakePins(-1), "7")
+	if err := e.Start(); err != errRead {
+		t.Errorf("Start returned %v, want the read error", err)
+	}
+	if err := e.Halt(); err != nil {
+		t.Errorf("Halt without starting returned %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package odometry
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultWindow is how long the speed is averaged over.
+const DefaultWindow = 250 * time.Millisecond
+
+// MetresPerPulse returns how far the car goes for each pulse of an encoder
+// giving pulsesPerRev pulses for each turn of what it is on, which turns
+// ratio times for each turn of a wheel of diameter metres. For an encoder on
+// the wheel itself ratio is 1.
+func MetresPerPulse(diameter, pulsesPerRev, ratio float64) float64 {
+	return math.Pi * diameter / (pulsesPerRev * ratio)
+}
+
+// Odometer works out the speed and distance of the car from the pulses of an
+// encoder. Call Update regularly, such as from the drive loop. It is safe to
+// use from multiple goroutines.
+type Odometer struct {
+	Counter        Counter
+	MetresPerPulse float64
+
+	// Window is how long the speed is averaged over. A longer window gives a
+	// smoother speed from fewer pulses, but lags behind more.
+	Window time.Duration
+
+	mu       sync.Mutex
+	start    uint64
+	samples  []sample
+	speed    float64
+	distance float64
+}
+
+type sample struct {
+	t     time.Time
+	count uint64
+}
+
+// NewOdometer returns an Odometer that counts the distance from the current
+// count of c.
+func NewOdometer(c Counter, metresPerPulse float64) *Odometer {
+	return &Odometer{
+		Counter:        c,
+		MetresPerPulse: metresPerPulse,
+		Window:         DefaultWindow,
+		start:          c.Count(),
+	}
+}
+
+// Update reads the counter at now, and returns the speed in metres/second
+// and the distance in metres since the Odometer was made.
+func (o *Odometer) Update(now time.Time) (speed, distance float64) {
+	count := o.Counter.Count()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.samples = append(o.samples, sample{now, count})
+	// keep the newest sample that is at least Window old to measure from
+	old := 0
+	for old+1 < len(o.samples) && now.Sub(o.samples[old+1].t) >= o.Window {
+		old++
+	}
+	o.samples = o.samples[old:]
+
+	first := o.samples[0]
+	if dt := now.Sub(first.t).Seconds(); dt > 0 {
+		o.speed = float64(count-first.count) * o.MetresPerPulse / dt
+	}
+	o.distance = float64(count-o.start) * o.MetresPerPulse
+	return o.speed, o.distance
+}
+
+// Speed returns the speed in metres/second at the last Update.
+func (o *Odometer) Speed() float64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.speed
+}
+
+// Distance returns the distance in metres at the last Update.
+func (o *Odometer) Distance() float64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.distance
+}
@@ -0,0 +1,53 @@
+package odometry
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type fakeCounter uint64
+
+func (c *fakeCounter) Count() uint64 { return uint64(*c) }
+
+func TestOdometer(t *testing.T) {
+	start := time.Date(2018, 8, 28, 10, 0, 0, 0, time.UTC)
+	ms := func(n int) time.Time { return start.Add(time.Duration(n) * time.Millisecond) }
+
+	counter := fakeCounter(100)
+	o := NewOdometer(&counter, 0.01)
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		count    uint64
+		speed    float64
+		distance float64
+	}{
+		{"first update", ms(0), 100, 0, 0},
+		{"inside the window", ms(100), 110, 1, 0.1},
+		{"a window after the start", ms(250), 120, 0.8, 0.2},
+		// the update at 100ms is exactly a window old, so the speed is
+		// measured from it rather than from the start
+		{"window edge", ms(350), 140, 1.2, 0.4},
+		{"no pulses", ms(600), 140, 0, 0.4},
+		{"pulses again", ms(700), 145, 0.05 / 0.35, 0.45},
+	}
+	for _, tt := range tests {
+		counter = fakeCounter(tt.count)
+		speed, distance := o.Update(tt.at)
+		if math.Abs(speed-tt.speed) > 1e-9 || math.Abs(distance-tt.distance) > 1e-9 {
+			t.Errorf("%s: speed %.3f, distance %.3f, want %.3f, %.3f", tt.name, speed, distance, tt.speed, tt.distance)
+		}
+		if o.Speed() != speed || o.Distance() != distance {
+			t.Errorf("%s: Speed and Distance give %.3f, %.3f", tt.name, o.Speed(), o.Distance())
+		}
+	}
+}
+
+func TestMetresPerPulse(t *testing.T) {
+	// 20 pulses a turn of the motor, geared 5:1 to a 110mm wheel
+	if got, want := MetresPerPulse(0.11, 20, 5), math.Pi*0.11/100; math.Abs(got-want) > 1e-12 {
+		t.Errorf("MetresPerPulse = %g, want %g", got, want)
+	}
+}
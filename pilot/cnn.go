@@ -0,0 +1,51 @@
+package pilot
+
+import (
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/model"
+)
+
+// CNN is a pilot that drives with a model trained on recorded tubs, using
+// both the steering and the throttle it predicts.
+type CNN struct {
+	Model *model.Model
+}
+
+// LoadCNN returns a CNN pilot with the model saved at path.
+func LoadCNN(path string) (*CNN, error) {
+	m, err := model.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &CNN{Model: m}, nil
+}
+
+// Run predicts the steering and throttle for a BGR frame.
+func (p *CNN) Run(img gocv.Mat) Output {
+	if img.Empty() {
+		return Output{}
+	}
+	input := p.Model.Input.FromBGR(img.ToBytes(), img.Cols(), img.Rows())
+	steering, throttle := p.Model.Predict(input)
+	return Output{
+		Steering: clamp(steering, -1, 1),
+		Throttle: clamp(throttle, -1, 1),
+		OK:       true,
+	}
+}
+
+// Close does nothing, as the model holds no Mats.
+func (p *CNN) Close() error {
+	return nil
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
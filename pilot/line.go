@@ -0,0 +1,53 @@
+package pilot
+
+import (
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/vision"
+)
+
+// DefaultLineGain is the steering for each unit the line is off centre.
+const DefaultLineGain = -7
+
+// Line is the line following pilot that completed the course at Gophercon
+// 2018. It steers towards the line found by a vision.LineFollower, and drives
+// at a fixed throttle.
+type Line struct {
+	Follower *vision.LineFollower
+
+	// Gain is multiplied with how far the line is off centre to get the
+	// steering, and Throttle is the throttle while the line is found.
+	Gain     float64
+	Throttle float64
+
+	// Stage, if set, is called with each stage of the vision processing.
+	Stage vision.StageFunc
+}
+
+// NewLine returns a Line pilot that drives at throttle.
+func NewLine(throttle float64) *Line {
+	return &Line{
+		Follower: vision.NewLineFollower(),
+		Gain:     DefaultLineGain,
+		Throttle: throttle,
+	}
+}
+
+// Run finds the line in img and steers towards it.
+func (l *Line) Run(img gocv.Mat) Output {
+	offset, found := l.Follower.Process(img, l.Stage)
+	if !found {
+		return Output{}
+	}
+	return Output{Steering: offset * l.Gain, Throttle: l.Throttle, OK: true}
+}
+
+// Annotated returns the cropped frame with the line drawn on it.
+func (l *Line) Annotated() gocv.Mat {
+	return l.Follower.Annotated()
+}
+
+// Close frees the Mats of the line follower.
+func (l *Line) Close() error {
+	return l.Follower.Close()
+}
@@ -0,0 +1,31 @@
+// Package pilot has the autopilots that work out the steering and throttle
+// of a car from its camera frames.
+package pilot
+
+import (
+	"gocv.io/x/gocv"
+)
+
+// Output is what a Pilot decided to do for a frame. OK is false if the pilot
+// could not decide, for example because the line was lost, in which case the
+// car keeps its last steering and throttle.
+type Output struct {
+	Steering float64
+	Throttle float64
+	OK       bool
+}
+
+// Pilot drives the car from camera frames.
+type Pilot interface {
+	// Run works out the steering and throttle for a frame. It must not
+	// modify or keep the frame.
+	Run(img gocv.Mat) Output
+
+	Close() error
+}
+
+// Annotator is a Pilot that draws what it found in the latest frame, to be
+// streamed instead of the camera frame.
+type Annotator interface {
+	Annotated() gocv.Mat
+}
@@ -0,0 +1,208 @@
+// Package runlog writes a structured log of each run of a car, so that runs
+// can be looked into after the fact. Every entry is written as one JSON object
+// per line to a file for the run, and the more important ones are also printed
+// to the console.
+package runlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the importance of a log entry.
+type Level int
+
+// Log levels, from least to most important.
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// MarshalJSON writes the Level as its name.
+func (l Level) MarshalJSON() ([]byte, error) {
+	return json.Marshal(l.String())
+}
+
+// Fields are the structured data of a log entry.
+type Fields map[string]interface{}
+
+// Entry is a single line of the log file.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Level   Level     `json:"level"`
+	Message string    `json:"msg"`
+	Fields  Fields    `json:"fields,omitempty"`
+}
+
+// Logger writes log entries. A nil *Logger prints to the console only.
+type Logger struct {
+	// FileLevel and ConsoleLevel are the least important levels written to
+	// the log file and the console.
+	FileLevel    Level
+	ConsoleLevel Level
+
+	mu      sync.Mutex
+	file    io.WriteCloser
+	console io.Writer
+	enc     *json.Encoder
+	limits  map[string]*limit
+}
+
+type limit struct {
+	last       time.Time
+	suppressed int
+}
+
+// New returns a Logger that writes JSON lines to file and a readable form to
+// console. Either may be nil.
+func New(file io.WriteCloser, console io.Writer) *Logger {
+	l := &Logger{
+		FileLevel:    Debug,
+		ConsoleLevel: Info,
+		file:         file,
+		console:      console,
+		limits:       make(map[string]*limit),
+	}
+	if file != nil {
+		l.enc = json.NewEncoder(file)
+	}
+	return l
+}
+
+// Open creates a new log file for a run in dir, named after the car and the
+// time the run started, and returns a Logger that writes to it and to stdout.
+// If a log for a run started in the same second already exists, a number is
+// added to the name rather than overwriting it.
+func Open(dir, car string) (*Logger, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%s-%s", car, time.Now().Format("20060102-150405")))
+	name := base + ".jsonl"
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return New(f, os.Stdout), nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		name = fmt.Sprintf("%s-%d.jsonl", base, i)
+	}
+}
+
+// Close closes the log file.
+func (l *Logger) Close() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Log writes an entry.
+func (l *Logger) Log(level Level, msg string, fields Fields) {
+	e := Entry{Time: time.Now(), Level: level, Message: msg, Fields: fields}
+
+	if l == nil {
+		if level >= Info {
+			writeConsole(os.Stdout, e)
+		}
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.enc != nil && level >= l.FileLevel {
+		l.enc.Encode(e)
+	}
+	if l.console != nil && level >= l.ConsoleLevel {
+		writeConsole(l.console, e)
+	}
+}
+
+// Debug writes an entry at Debug level.
+func (l *Logger) Debug(msg string, fields Fields) { l.Log(Debug, msg, fields) }
+
+// Info writes an entry at Info level.
+func (l *Logger) Info(msg string, fields Fields) { l.Log(Info, msg, fields) }
+
+// Warn writes an entry at Warn level.
+func (l *Logger) Warn(msg string, fields Fields) { l.Log(Warn, msg, fields) }
+
+// Error writes an entry at Error level.
+func (l *Logger) Error(msg string, fields Fields) { l.Log(Error, msg, fields) }
+
+// Limit writes an entry at most once every interval for each key, so that
+// something failing in a fast loop does not flood the log. The number of
+// entries left out since the last one is added as the "suppressed" field.
+func (l *Logger) Limit(key string, every time.Duration, level Level, msg string, fields Fields) {
+	if l == nil {
+		l.Log(level, msg, fields)
+		return
+	}
+
+	l.mu.Lock()
+	lim, ok := l.limits[key]
+	if !ok {
+		lim = &limit{}
+		l.limits[key] = lim
+	}
+	now := time.Now()
+	if now.Sub(lim.last) < every {
+		lim.suppressed++
+		l.mu.Unlock()
+		return
+	}
+	suppressed := lim.suppressed
+	lim.last = now
+	lim.suppressed = 0
+	l.mu.Unlock()
+
+	if suppressed > 0 {
+		f := Fields{"suppressed": suppressed}
+		for k, v := range fields {
+			f[k] = v
+		}
+		fields = f
+	}
+	l.Log(level, msg, fields)
+}
+
+// writeConsole prints an entry as one readable line.
+func writeConsole(w io.Writer, e Entry) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s %s", e.Time.Format("15:04:05"), strings.ToUpper(e.Level.String()), e.Message)
+
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, " %s=%v", k, e.Fields[k])
+	}
+	fmt.Fprintln(w, b.String())
+}
@@ -0,0 +1,32 @@
+package runlog
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestOpenSameSecond(t *testing.T) {
+	dir, err := ioutil.TempDir("", "runlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// three runs started at once must each get their own log
+	for i := 0; i < 3; i++ {
+		l, err := Open(dir, "car")
+		if err != nil {
+			t.Fatal(err)
+		}
+		l.Close()
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("got %d log files, want 3", len(files))
+	}
+}
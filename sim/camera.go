@@ -0,0 +1,100 @@
+package sim
+
+import (
+	"image"
+	"math"
+)
+
+// Sky is the grey level of everything above the horizon.
+const Sky uint8 = 50
+
+// Camera is a pinhole camera fixed to the car, looking forward and down at
+// the track.
+type Camera struct {
+	// Width and Height are the size of the image in pixels.
+	Width, Height int
+
+	// Height above the floor and Forward of the rear axle are in metres,
+	// Pitch is how far it looks down in radians, and FOV is the horizontal
+	// field of view in radians.
+	Mount   float64
+	Forward float64
+	Pitch   float64
+	FOV     float64
+
+	// UpsideDown rotates the image by 180 degrees, as when the camera is
+	// mounted with its cable at the top.
+	UpsideDown bool
+
+	// rays holds where each pixel looks at on the floor, relative to the
+	// camera, or NaN for the sky.
+	rays []ray
+}
+
+type ray struct {
+	forward, right float64
+}
+
+// NewCamera returns a wide angle camera like the one on a Donkeycar, that
+// gives images the size of the ones recorded to a tub.
+func NewCamera() *Camera {
+	return &Camera{
+		Width:      160,
+		Height:     120,
+		Mount:      0.2,
+		Forward:    0.25,
+		Pitch:      30 * math.Pi / 180,
+		FOV:        120 * math.Pi / 180,
+		UpsideDown: true,
+	}
+}
+
+// project works out the rays of the pixels.
+func (c *Camera) project() {
+	c.rays = make([]ray, c.Width*c.Height)
+	f := float64(c.Width) / 2 / math.Tan(c.FOV/2)
+	sin, cos := math.Sincos(c.Pitch)
+	for v := 0; v < c.Height; v++ {
+		for u := 0; u < c.Width; u++ {
+			x := (float64(u) + 0.5 - float64(c.Width)/2) / f
+			y := (float64(v) + 0.5 - float64(c.Height)/2) / f
+
+			r := ray{forward: math.NaN()}
+			forward := cos - y*sin
+			down := sin + y*cos
+			if down > 1e-3 {
+				t := c.Mount / down
+				r = ray{forward: t * forward, right: t * x}
+			}
+			c.rays[v*c.Width+u] = r
+		}
+	}
+}
+
+// Render draws what the camera sees from a car at pose on the track into img,
+// which is allocated if it is nil or the wrong size, and returns it.
+func (c *Camera) Render(t *Track, pose Pose, img *image.Gray) *image.Gray {
+	if len(c.rays) != c.Width*c.Height {
+		c.project()
+	}
+	if img == nil || img.Bounds().Dx() != c.Width || img.Bounds().Dy() != c.Height {
+		img = image.NewGray(image.Rect(0, 0, c.Width, c.Height))
+	}
+
+	sin, cos := math.Sincos(pose.Heading)
+	x0, y0 := pose.X+c.Forward*cos, pose.Y+c.Forward*sin
+	n := len(c.rays)
+	for i, r := range c.rays {
+		v := Sky
+		if !math.IsNaN(r.forward) {
+			// right is clockwise from the heading
+			v = t.At(x0+r.forward*cos+r.right*sin, y0+r.forward*sin-r.right*cos)
+		}
+		j := i
+		if c.UpsideDown {
+			j = n - 1 - i
+		}
+		img.Pix[j/c.Width*img.Stride+j%c.Width] = v
+	}
+	return img
+}
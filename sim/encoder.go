@@ -0,0 +1,34 @@
+package sim
+
+import "sync"
+
+// Encoder is a wheel encoder on the simulated car, giving PulsesPerMetre
+// pulses for each metre driven. Like a single channel encoder it counts up
+// whichever way the car goes, and it keeps counting over a Reset.
+type Encoder struct {
+	Sim            *Sim
+	PulsesPerMetre float64
+
+	mu     sync.Mutex
+	offset float64
+	last   float64
+}
+
+// NewEncoder returns an Encoder on the car of s.
+func NewEncoder(s *Sim, pulsesPerMetre float64) *Encoder {
+	return &Encoder{Sim: s, PulsesPerMetre: pulsesPerMetre}
+}
+
+// Count returns the number of pulses since the start.
+func (e *Encoder) Count() uint64 {
+	d := e.Sim.Status().Distance
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if d < e.last {
+		// the sim was reset
+		e.offset += e.last
+	}
+	e.last = d
+	return uint64((e.offset + d) * e.PulsesPerMetre)
+}
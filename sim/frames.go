@@ -0,0 +1,61 @@
+package sim
+
+import (
+	"image"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// Frames reads the view of the simulated camera as BGR frames, at the frame
+// rate of a webcam.
+type Frames struct {
+	Sim      *Sim
+	Interval time.Duration
+
+	next time.Time
+	gray *image.Gray
+	bgr  []byte
+}
+
+// NewFrames returns a Frames for s at 20 frames per second.
+func NewFrames(s *Sim) *Frames {
+	return &Frames{Sim: s, Interval: 50 * time.Millisecond}
+}
+
+// Read waits for the next frame and renders it into m. It returns false once
+// the Sim is closed.
+func (f *Frames) Read(m *gocv.Mat) bool {
+	if wait := time.Until(f.next); wait > 0 {
+		select {
+		case <-f.Sim.done:
+			return false
+		case <-time.After(wait):
+		}
+	}
+	f.next = time.Now().Add(f.Interval)
+
+	select {
+	case <-f.Sim.done:
+		return false
+	default:
+	}
+
+	f.gray = f.Sim.Render(f.gray)
+	b := f.gray.Bounds()
+	if len(f.bgr) != 3*len(f.gray.Pix) {
+		f.bgr = make([]byte, 3*len(f.gray.Pix))
+	}
+	for i, v := range f.gray.Pix {
+		f.bgr[3*i], f.bgr[3*i+1], f.bgr[3*i+2] = v, v, v
+	}
+
+	// the Mat made from the bytes shares them, so copy it into m
+	frame, err := gocv.NewMatFromBytes(b.Dy(), b.Dx(), gocv.MatTypeCV8UC3, f.bgr)
+	if err != nil {
+		return false
+	}
+	defer frame.Close()
+	frame.CopyTo(m)
+	return true
+}
@@ -0,0 +1,205 @@
+// Package sim is a 2D simulator that stands in for the car hardware, so that
+// the pilot can be driven round a track without a Pi. It moves a kinematic
+// bicycle model of the car on a top down map of the track with the steering
+// and throttle sent to it, and renders what the camera would see and what the
+// IMU would measure.
+//
+// A Sim is a car.Actuator and an imu.Sensor, and Frames is a
+// vision.FrameSource, so they plug into a car.Car in place of the PCA9685, the
+// MPU6050 and the webcam.
+package sim
+
+import (
+	"errors"
+	"image"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/gophercar/imu"
+)
+
+// Gravity is the acceleration of gravity in metres per second squared.
+const Gravity = 9.81
+
+// ErrClosed is returned by a Sim after Close.
+var ErrClosed = errors.New("sim: closed")
+
+// Status is the state of a Sim.
+type Status struct {
+	Time     time.Duration `json:"time"`
+	Pose     Pose          `json:"pose"`
+	Speed    float64       `json:"speed"`
+	Steering float64       `json:"steering"`
+	Throttle float64       `json:"throttle"`
+	OnTrack  bool          `json:"on_track"`
+
+	// Laps holds the time of each completed lap, and Distance the distance
+	// driven in metres.
+	Laps     []time.Duration `json:"laps"`
+	Distance float64         `json:"distance"`
+}
+
+// Sim is a simulated car on a track.
+type Sim struct {
+	Track   *Track
+	Vehicle *Vehicle
+	Camera  *Camera
+
+	// Step is the time step of the physics.
+	Step time.Duration
+
+	mu       sync.Mutex
+	steering float64
+	throttle float64
+	elapsed  time.Duration
+	lapStart time.Duration
+	laps     []time.Duration
+	distance float64
+	along    float64
+	closed   bool
+	done     chan struct{}
+}
+
+// New returns a Sim with a Donkeycar sized car at the start of the track.
+func New(t *Track) *Sim {
+	return &Sim{
+		Track:   t,
+		Vehicle: NewVehicle(t.Start),
+		Camera:  NewCamera(),
+		Step:    5 * time.Millisecond,
+		done:    make(chan struct{}),
+	}
+}
+
+// SetSteering sets the steering, from -1 (left) to 1 (right).
+func (s *Sim) SetSteering(steering float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	s.steering = steering
+	return nil
+}
+
+// SetThrottle sets the throttle, from -1 (reverse) to 1 (forward).
+func (s *Sim) SetThrottle(throttle float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	s.throttle = throttle
+	return nil
+}
+
+// Advance moves the simulation on by d.
+func (s *Sim) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for end := s.elapsed + d; s.elapsed < end; s.elapsed += s.Step {
+		s.step()
+	}
+}
+
+// step moves the car on by one time step. It must be called with s.mu held.
+func (s *Sim) step() {
+	v := s.Vehicle
+	x, y := v.Pose.X, v.Pose.Y
+	v.Step(s.steering, s.throttle, s.Step.Seconds())
+	s.distance += math.Hypot(v.Pose.X-x, v.Pose.Y-y)
+
+	// a lap is done each time the car drives forward over the start line
+	along, across := s.Track.startOffset(v.Pose.X, v.Pose.Y)
+	if s.along < 0 && along >= 0 && math.Abs(across) <= s.Track.StartWidth/2 {
+		now := s.elapsed + s.Step
+		s.laps = append(s.laps, now-s.lapStart)
+		s.lapStart = now
+	}
+	s.along = along
+}
+
+// Reset puts the car back at the start of the track, and clears the laps.
+func (s *Sim) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.Vehicle
+	v.Pose = s.Track.Start
+	v.Speed, v.Steer, v.YawRate, v.Accel, v.LateralAccel = 0, 0, 0, 0, 0
+	s.steering, s.throttle = 0, 0
+	s.lapStart = s.elapsed
+	s.laps = nil
+	s.distance = 0
+	s.along = 0
+}
+
+// Run advances the simulation in real time until Close is called.
+func (s *Sim) Run() {
+	ticker := time.NewTicker(s.Step)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.Advance(s.Step)
+		}
+	}
+}
+
+// Close stops Run, and makes the actuators and sensors return ErrClosed.
+func (s *Sim) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+	return nil
+}
+
+// Status returns the state of the simulation.
+func (s *Sim) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v := s.Vehicle
+	return Status{
+		Time:     s.elapsed,
+		Pose:     v.Pose,
+		Speed:    v.Speed,
+		Steering: s.steering,
+		Throttle: s.throttle,
+		OnTrack:  s.Track.OnTrack(v.Pose.X, v.Pose.Y),
+		Laps:     append([]time.Duration(nil), s.laps...),
+		Distance: s.distance,
+	}
+}
+
+// Read returns what an IMU fixed flat to the car measures, with x forward, y
+// to the left and z up.
+func (s *Sim) Read() (imu.Reading, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return imu.Reading{}, ErrClosed
+	}
+	v := s.Vehicle
+	return imu.Reading{
+		Accel: imu.Vector{
+			X: v.Accel / Gravity,
+			Y: v.LateralAccel / Gravity,
+			Z: 1,
+		},
+		Gyro:        imu.Vector{Z: v.YawRate * 180 / math.Pi},
+		Temperature: 25,
+	}, nil
+}
+
+// Render draws what the camera sees into img, as Camera.Render.
+func (s *Sim) Render(img *image.Gray) *image.Gray {
+	s.mu.Lock()
+	pose := s.Vehicle.Pose
+	s.mu.Unlock()
+	return s.Camera.Render(s.Track, pose, img)
+}
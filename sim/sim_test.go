@@ -0,0 +1,50 @@
+package sim_test
+
+import (
+	"testing"
+	"time"
+
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/pilot"
+	"github.com/hybridgroup/gophercar/sim"
+)
+
+// TestLineLap drives the line pilot round the oval in simulated time, with
+// each frame steering the car until the next one, and checks that it gets
+// round a lap without leaving the track.
+func TestLineLap(t *testing.T) {
+	s := sim.New(sim.Oval(4, 1.5))
+	defer s.Close()
+	frames := sim.NewFrames(s)
+	frames.Interval = 0
+
+	p := pilot.NewLine(0.2)
+	defer p.Close()
+	img := gocv.NewMat()
+	defer img.Close()
+
+	const frame = 50 * time.Millisecond
+	for st := s.Status(); len(st.Laps) == 0; st = s.Status() {
+		if st.Time > time.Minute {
+			t.Fatalf("no lap after %v, %.1fm driven", st.Time, st.Distance)
+		}
+		if !st.OnTrack {
+			t.Fatalf("left the track at %.2f, %.2f after %.1fm", st.Pose.X, st.Pose.Y, st.Distance)
+		}
+
+		if !frames.Read(&img) {
+			t.Fatal("no frame from the sim")
+		}
+		if out := p.Run(img); out.OK {
+			s.SetSteering(out.Steering)
+			s.SetThrottle(out.Throttle)
+		}
+		s.Advance(frame)
+	}
+
+	st := s.Status()
+	if st.Distance < 2*4 {
+		t.Errorf("a lap of %.1fm is shorter than the straights", st.Distance)
+	}
+}
@@ -0,0 +1,156 @@
+package sim
+
+import (
+	"image"
+	"image/draw"
+	_ "image/jpeg" // so LoadTrack can read JPEG maps
+	_ "image/png"  // and PNG maps
+	"math"
+	"os"
+)
+
+// Grey levels of a track map. Anything darker than OffTrack is outside the
+// track.
+const (
+	Outside  uint8 = 15
+	OffTrack uint8 = 30
+	Floor    uint8 = 60
+	Line     uint8 = 235
+)
+
+// Pose is a position in metres and a heading in radians, counterclockwise
+// from the x axis.
+type Pose struct {
+	X, Y    float64
+	Heading float64
+}
+
+// Track is a top down map of the floor, with the line to follow painted on it.
+type Track struct {
+	// Map is the grey level of the floor. Its top left corner is at Origin,
+	// and each pixel is Scale metres wide.
+	Map    *image.Gray
+	Origin Pose
+	Scale  float64
+
+	// Start is where the car starts, and laps are counted each time it
+	// crosses the start line, which is StartWidth metres wide.
+	Start      Pose
+	StartWidth float64
+
+	// cte returns the distance from the centre of the track, if it is known.
+	cte func(x, y float64) float64
+}
+
+// Oval returns a stadium shaped track with two straights of the given length
+// joined by half circles of radius, all in metres, with the line down the
+// middle. The car starts in the middle of the bottom straight, driving
+// counterclockwise.
+func Oval(straight, radius float64) *Track {
+	const (
+		scale      = 0.01
+		lineWidth  = 0.05
+		trackWidth = 1.2
+		margin     = 1.0
+	)
+
+	halfW := straight/2 + radius + margin
+	halfH := radius + margin
+	w, h := int(2*halfW/scale), int(2*halfH/scale)
+	t := &Track{
+		Map:        image.NewGray(image.Rect(0, 0, w, h)),
+		Origin:     Pose{X: -halfW, Y: halfH},
+		Scale:      scale,
+		Start:      Pose{X: 0, Y: -radius},
+		StartWidth: trackWidth,
+	}
+	t.cte = func(x, y float64) float64 {
+		if math.Abs(x) <= straight/2 {
+			return math.Abs(y) - radius
+		}
+		cx := math.Copysign(straight/2, x)
+		return math.Hypot(x-cx, y) - radius
+	}
+
+	for py := 0; py < h; py++ {
+		for px := 0; px < w; px++ {
+			x, y := t.world(px, py)
+
+			d := math.Abs(t.cte(x, y))
+
+			v := Outside
+			switch {
+			case d <= lineWidth/2:
+				v = Line
+			case d <= trackWidth/2:
+				v = Floor
+			}
+			t.Map.Pix[py*t.Map.Stride+px] = v
+		}
+	}
+	return t
+}
+
+// LoadTrack reads a map from an image file, where each pixel is scale metres
+// wide and the bottom left corner is at 0, 0. Bright pixels are the line, and
+// pixels darker than OffTrack are outside of the track.
+func LoadTrack(path string, scale float64, start Pose) (*Track, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	gray := image.NewGray(img.Bounds())
+	draw.Draw(gray, gray.Bounds(), img, img.Bounds().Min, draw.Src)
+
+	return &Track{
+		Map:        gray,
+		Origin:     Pose{X: 0, Y: float64(gray.Bounds().Dy()) * scale},
+		Scale:      scale,
+		Start:      start,
+		StartWidth: 1.2,
+	}, nil
+}
+
+// At returns the grey level of the floor at x, y.
+func (t *Track) At(x, y float64) uint8 {
+	px := int((x - t.Origin.X) / t.Scale)
+	py := int((t.Origin.Y - y) / t.Scale)
+	b := t.Map.Bounds()
+	if px < b.Min.X || px >= b.Max.X || py < b.Min.Y || py >= b.Max.Y {
+		return Outside
+	}
+	return t.Map.Pix[(py-b.Min.Y)*t.Map.Stride+px-b.Min.X]
+}
+
+// OnTrack returns true if x, y is on the track.
+func (t *Track) OnTrack(x, y float64) bool {
+	return t.At(x, y) >= OffTrack
+}
+
+// CTE returns the cross track error at x, y: how far it is from the centre
+// line, positive outside of the oval. The second return value is false if the
+// track does not know where its centre line is.
+func (t *Track) CTE(x, y float64) (float64, bool) {
+	if t.cte == nil {
+		return 0, false
+	}
+	return t.cte(x, y), true
+}
+
+// world returns the position of the centre of a map pixel.
+func (t *Track) world(px, py int) (x, y float64) {
+	return t.Origin.X + (float64(px)+0.5)*t.Scale, t.Origin.Y - (float64(py)+0.5)*t.Scale
+}
+
+// startOffset returns how far p is along and across the start line.
+func (t *Track) startOffset(x, y float64) (along, across float64) {
+	dx, dy := x-t.Start.X, y-t.Start.Y
+	sin, cos := math.Sincos(t.Start.Heading)
+	return dx*cos + dy*sin, -dx*sin + dy*cos
+}
@@ -0,0 +1,83 @@
+package sim
+
+import (
+	"math"
+)
+
+// Vehicle is a kinematic bicycle model of the car.
+type Vehicle struct {
+	// WheelBase is the distance between the axles in metres, and MaxSteer
+	// the steering angle in radians at full lock.
+	WheelBase float64
+	MaxSteer  float64
+
+	// MaxSpeed is the speed in metres per second at full throttle, and
+	// SpeedLag and SteerLag are the time constants in seconds for the motor
+	// and the servo to catch up with what they are asked for.
+	MaxSpeed float64
+	SpeedLag float64
+	SteerLag float64
+
+	// State
+	Pose  Pose
+	Speed float64
+	Steer float64
+
+	// YawRate is in radians per second counterclockwise, and Accel and
+	// LateralAccel in metres per second squared, forward and to the left.
+	YawRate      float64
+	Accel        float64
+	LateralAccel float64
+}
+
+// NewVehicle returns a Vehicle the size of a Donkeycar at pose.
+func NewVehicle(pose Pose) *Vehicle {
+	return &Vehicle{
+		WheelBase: 0.26,
+		MaxSteer:  25 * math.Pi / 180,
+		MaxSpeed:  4,
+		SpeedLag:  0.3,
+		SteerLag:  0.05,
+		Pose:      pose,
+	}
+}
+
+// Step moves the vehicle on by dt seconds with the steering and throttle,
+// both from -1 to 1. Positive steering turns right.
+func (v *Vehicle) Step(steering, throttle, dt float64) {
+	steering = clamp(steering, -1, 1)
+	throttle = clamp(throttle, -1, 1)
+
+	v.Steer += (steering*v.MaxSteer - v.Steer) * lag(dt, v.SteerLag)
+	speed := v.Speed + (throttle*v.MaxSpeed-v.Speed)*lag(dt, v.SpeedLag)
+	v.Accel = (speed - v.Speed) / dt
+	v.Speed = speed
+
+	// steering right turns clockwise
+	v.YawRate = -v.Speed * math.Tan(v.Steer) / v.WheelBase
+	v.LateralAccel = v.Speed * v.YawRate
+
+	v.Pose.Heading = math.Remainder(v.Pose.Heading+v.YawRate*dt, 2*math.Pi)
+	sin, cos := math.Sincos(v.Pose.Heading)
+	v.Pose.X += v.Speed * cos * dt
+	v.Pose.Y += v.Speed * sin * dt
+}
+
+// lag returns the fraction of the way a first order lag with time constant
+// tau moves in dt.
+func lag(dt, tau float64) float64 {
+	if tau <= 0 {
+		return 1
+	}
+	return 1 - math.Exp(-dt/tau)
+}
+
+func clamp(v, min, max float64) float64 {
+	switch {
+	case v < min:
+		return min
+	case v > max:
+		return max
+	}
+	return v
+}
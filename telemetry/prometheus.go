@@ -0,0 +1,61 @@
+package telemetry
+
+import (
+	"io"
+
+	"github.com/hybridgroup/gophercar/imu"
+	"github.com/hybridgroup/gophercar/metrics"
+)
+
+// WritePrometheus writes the current Data as Prometheus metrics.
+func (t *Telemetry) WritePrometheus(w io.Writer) {
+	d := t.Get()
+
+	metrics.WriteMetric(w, "mode", metrics.Gauge, "Drive mode of the car, the current mode is 1.",
+		metrics.Sample{Labels: metrics.Label("mode", d.Mode), Value: 1})
+	metrics.WriteValue(w, "steering", metrics.Gauge, "Steering sent to the servo, from -1 (left) to 1 (right).", d.Steering)
+	metrics.WriteValue(w, "throttle", metrics.Gauge, "Throttle sent to the ESC, from -1 (reverse) to 1 (forward).", d.Throttle)
+
+	metrics.WriteValue(w, "frames_captured_total", metrics.Counter, "Frames read from the camera.", float64(d.Frames.Captured))
+	metrics.WriteValue(w, "frames_processed_total", metrics.Counter, "Frames the vision was run on.", float64(d.Frames.Processed))
+	metrics.WriteValue(w, "frames_streamed_total", metrics.Counter, "Frames encoded for the video stream.", float64(d.Frames.Streamed))
+	metrics.WriteMetric(w, "frames_dropped_total", metrics.Counter, "Frames replaced by a newer one before the next stage got to them.",
+		metrics.Sample{Labels: metrics.Label("stage", "vision"), Value: float64(d.Frames.Dropped)},
+		metrics.Sample{Labels: metrics.Label("stage", "stream"), Value: float64(d.Frames.StreamDropped)})
+	metrics.WriteValue(w, "fps", metrics.Gauge, "Rate frames are processed at.", d.FPS)
+	metrics.WriteValue(w, "latency_seconds", metrics.Gauge, "Time from reading the latest frame to sending its steering to the servo.", d.LatencyMS/1000)
+
+	metrics.WriteValue(w, "line_found", metrics.Gauge, "1 if the line was found in the latest frame.", boolValue(d.LineFound))
+	metrics.WriteValue(w, "line_lost_total", metrics.Counter, "Frames in which the line could not be found.", float64(d.LineLost))
+
+	metrics.WriteMetric(w, "imu_accel_g", metrics.Gauge, "Calibrated acceleration from the MPU6050.", axes(d.IMU.Accel)...)
+	metrics.WriteMetric(w, "imu_gyro_degrees_per_second", metrics.Gauge, "Calibrated rotation rate from the MPU6050.", axes(d.IMU.Gyro)...)
+	metrics.WriteValue(w, "imu_temperature_celsius", metrics.Gauge, "Temperature of the MPU6050.", d.IMU.Temperature)
+	metrics.WriteMetric(w, "attitude_degrees", metrics.Gauge, "Estimated orientation of the car.",
+		metrics.Sample{Labels: metrics.Label("axis", "roll"), Value: d.Attitude.Roll},
+		metrics.Sample{Labels: metrics.Label("axis", "pitch"), Value: d.Attitude.Pitch},
+		metrics.Sample{Labels: metrics.Label("axis", "yaw"), Value: d.Attitude.Yaw})
+	metrics.WriteValue(w, "yaw_rate_degrees_per_second", metrics.Gauge, "Estimated yaw rate, counterclockwise positive.", d.Attitude.YawRate)
+
+	metrics.WriteValue(w, "watchdog_trips_total", metrics.Counter, "Times a watchdog has stopped the car.", float64(d.WatchdogTrips))
+	metrics.WriteValue(w, "crashed", metrics.Gauge, "1 while a detected crash is waiting for an operator reset.", boolValue(d.Crash != nil))
+
+	metrics.WriteValue(w, "recording", metrics.Gauge, "1 while driving data is being recorded.", boolValue(d.Recording))
+	metrics.WriteValue(w, "records", metrics.Gauge, "Number of records saved in this run.", float64(d.Records))
+	metrics.WriteValue(w, "cpu_temperature_celsius", metrics.Gauge, "Temperature of the Raspberry Pi CPU.", d.CPUTemperature)
+}
+
+func axes(v imu.Vector) []metrics.Sample {
+	return []metrics.Sample{
+		{Labels: metrics.Label("axis", "x"), Value: v.X},
+		{Labels: metrics.Label("axis", "y"), Value: v.Y},
+		{Labels: metrics.Label("axis", "z"), Value: v.Z},
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
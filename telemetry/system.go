@@ -0,0 +1,42 @@
+package telemetry
+
+import (
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// CPUThermalZone is where the Raspberry Pi reports the CPU temperature, in
+// thousandths of a degree Celsius.
+const CPUThermalZone = "/sys/class/thermal/thermal_zone0/temp"
+
+// CPUTemperature returns the CPU temperature in degrees Celsius.
+func CPUTemperature() (float64, error) {
+	data, err := ioutil.ReadFile(CPUThermalZone)
+	if err != nil {
+		return 0, err
+	}
+
+	milli, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, err
+	}
+	return milli / 1000, nil
+}
+
+// LocalIP returns the first non-loopback IPv4 address of the car, or an empty
+// string if it has none.
+func LocalIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil {
+			return ipnet.IP.String()
+		}
+	}
+	return ""
+}
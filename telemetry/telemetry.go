@@ -0,0 +1,118 @@
+// Package telemetry keeps the latest state of a running car, so that it can be
+// shared between the drive loop, displays and the web server.
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/gophercar/imu"
+)
+
+// Data is a snapshot of the car state.
+type Data struct {
+	Time     time.Time `json:"time"`
+	Mode     string    `json:"mode"`
+	Steering float64   `json:"steering"`
+	Throttle float64   `json:"throttle"`
+
+	// Speed is in metres/second and Odometer the distance driven in metres,
+	// if the car has a wheel encoder. TargetSpeed is the speed the cruise
+	// control is holding, or 0 when it is not driving.
+	Speed       float64 `json:"speed,omitempty"`
+	Odometer    float64 `json:"odometer,omitempty"`
+	TargetSpeed float64 `json:"target_speed,omitempty"`
+
+	IMU      imu.Reading  `json:"imu"`
+	Attitude imu.Attitude `json:"attitude"`
+
+	// Crash is set while a detected crash is waiting for an operator reset.
+	Crash *imu.Event `json:"crash,omitempty"`
+
+	// WatchdogTrips counts the times a watchdog, such as the crash detector,
+	// has stopped the car.
+	WatchdogTrips uint64 `json:"watchdog_trips"`
+
+	// FailedDevices names the I2C devices that have failed too many times in
+	// a row, while they are waiting for an operator reset.
+	FailedDevices []string `json:"failed_devices,omitempty"`
+
+	// FPS is the rate frames are processed at, and LatencyMS is how long in
+	// milliseconds it took from reading the latest frame to sending the
+	// steering worked out from it to the servo.
+	FPS       float64 `json:"fps"`
+	LatencyMS float64 `json:"latency_ms"`
+
+	Frames Frames `json:"frames"`
+
+	// LineFound is false when the vision could not find the line in the
+	// latest frame, and LineLost counts all of the frames where that happened.
+	LineFound bool   `json:"line_found"`
+	LineLost  uint64 `json:"line_lost"`
+
+	// Range is the distance in metres to the nearest obstacle ahead, if the
+	// car has a range sensor, and Obstacle is set while the car is slowing
+	// down or stopped for it.
+	Range    float64 `json:"range,omitempty"`
+	Obstacle bool    `json:"obstacle,omitempty"`
+
+	// Behaviour lists the objects the car is stopping, waiting or slowing
+	// down for, such as "wait: stop sign".
+	Behaviour string `json:"behaviour,omitempty"`
+
+	// Recording is true while driving data is being recorded, and Records is
+	// the number of records saved so far.
+	Recording bool `json:"recording"`
+	Records   int  `json:"records"`
+
+	// Address is the host:port of the car's web server, if it runs one.
+	Address        string  `json:"address,omitempty"`
+	CPUTemperature float64 `json:"cpu_temperature"`
+}
+
+// Frames counts the frames passing through the vision pipeline.
+type Frames struct {
+	Captured  uint64 `json:"captured"`
+	Processed uint64 `json:"processed"`
+	Streamed  uint64 `json:"streamed"`
+
+	// Dropped is the number of captured frames that were replaced by a newer
+	// one before the vision worker got to them, and StreamDropped is the same
+	// for annotated frames waiting for the stream encoder.
+	Dropped       uint64 `json:"dropped"`
+	StreamDropped uint64 `json:"stream_dropped"`
+}
+
+// Telemetry holds the latest Data and is safe to use from multiple goroutines.
+type Telemetry struct {
+	mu   sync.RWMutex
+	data Data
+}
+
+// New returns a new Telemetry.
+func New() *Telemetry {
+	return &Telemetry{}
+}
+
+// Update changes the current Data by calling fn with the lock held.
+func (t *Telemetry) Update(fn func(d *Data)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fn(&t.data)
+	t.data.Time = time.Now()
+}
+
+// Get returns a copy of the current Data.
+func (t *Telemetry) Get() Data {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.data
+}
+
+// ServeHTTP writes the current Data as JSON.
+func (t *Telemetry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.Get())
+}
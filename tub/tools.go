@@ -0,0 +1,167 @@
+package tub
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Summary is the smallest, mean and largest of a value.
+type Summary struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	Max  float64 `json:"max"`
+}
+
+func (s *Summary) add(v float64, n int) {
+	if n == 1 || v < s.Min {
+		s.Min = v
+	}
+	if n == 1 || v > s.Max {
+		s.Max = v
+	}
+	s.Mean += (v - s.Mean) / float64(n)
+}
+
+// Stats sums up the records of a tub.
+type Stats struct {
+	Records  int            `json:"records"`
+	First    int            `json:"first"`
+	Last     int            `json:"last"`
+	Duration time.Duration  `json:"duration"`
+	Modes    map[string]int `json:"modes"`
+
+	// ZeroThrottle is the number of records where the car is stopped.
+	ZeroThrottle int `json:"zero_throttle"`
+
+	Angle    Summary `json:"angle"`
+	Throttle Summary `json:"throttle"`
+
+	// Steering counts the records in each of 10 equal bins of the angle
+	// from -1 to 1, to show if the data is balanced.
+	Steering [10]int `json:"steering"`
+}
+
+// Summarize returns the Stats of records, which are in order.
+func Summarize(records []Record) Stats {
+	s := Stats{Records: len(records), Modes: make(map[string]int)}
+	if len(records) == 0 {
+		return s
+	}
+	first, last := records[0], records[len(records)-1]
+	s.First, s.Last = first.Index, last.Index
+	s.Duration = last.Time().Sub(first.Time())
+
+	for i, r := range records {
+		s.Modes[r.Mode]++
+		if r.Throttle == 0 {
+			s.ZeroThrottle++
+		}
+		s.Angle.add(r.Angle, i+1)
+		s.Throttle.add(r.Throttle, i+1)
+
+		bin := int((r.Angle + 1) / 2 * float64(len(s.Steering)))
+		bin = int(math.Max(0, math.Min(float64(bin), float64(len(s.Steering)-1))))
+		s.Steering[bin]++
+	}
+	return s
+}
+
+// Range is a range of record indexes, including both ends.
+type Range struct {
+	From, To int
+}
+
+// ParseRange reads a range written as from-to, or a single index.
+func ParseRange(s string) (Range, error) {
+	parts := strings.SplitN(s, "-", 2)
+	from, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return Range{}, fmt.Errorf("bad range %q", s)
+	}
+	to := from
+	if len(parts) == 2 {
+		if to, err = strconv.Atoi(parts[1]); err != nil || to < from {
+			return Range{}, fmt.Errorf("bad range %q", s)
+		}
+	}
+	return Range{From: from, To: to}, nil
+}
+
+// Contains returns true if i is in the range.
+func (r Range) Contains(i int) bool {
+	return i >= r.From && i <= r.To
+}
+
+// DeleteIf deletes the records for which del returns true, and returns how
+// many were deleted.
+func (t *Tub) DeleteIf(del func(r Record) bool) (int, error) {
+	records, err := t.Records()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, r := range records {
+		if !del(r) {
+			continue
+		}
+		if err := t.Delete(r.Index); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// Copy adds r, a record of src, with its image to t, and returns the new
+// record.
+func (t *Tub) Copy(src *Tub, r Record) (Record, error) {
+	if err := t.AddInputs(src.Meta); err != nil {
+		return r, err
+	}
+	jpeg, err := ioutil.ReadFile(src.ImagePath(r))
+	if err != nil {
+		return r, err
+	}
+	return t.Write(r, jpeg)
+}
+
+// Split copies the records of src to train and val, with about fraction of
+// them going to val. Records are kept together in runs of chunk, so that
+// val is not full of frames that are next to, and nearly the same as, frames
+// in train. It returns the number of records copied to each.
+func Split(src, train, val *Tub, fraction float64, chunk int, seed int64) (nTrain, nVal int, err error) {
+	records, err := src.Records()
+	if err != nil {
+		return 0, 0, err
+	}
+	if chunk < 1 {
+		chunk = 1
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	for start := 0; start < len(records); start += chunk {
+		dst, n := train, &nTrain
+		if rnd.Float64() < fraction {
+			dst, n = val, &nVal
+		}
+		for _, r := range records[start:min(start+chunk, len(records))] {
+			if _, err := dst.Copy(src, r); err != nil {
+				return nTrain, nVal, err
+			}
+			*n++
+		}
+	}
+	return nTrain, nVal, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
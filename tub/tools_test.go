@@ -0,0 +1,174 @@
+package tub
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Range
+		ok   bool
+	}{
+		{"5", Range{5, 5}, true},
+		{"3-7", Range{3, 7}, true},
+		{"4-4", Range{4, 4}, true},
+		{"7-3", Range{}, false},
+		{"", Range{}, false},
+		{"a", Range{}, false},
+		{"3-", Range{}, false},
+		{"-3", Range{}, false},
+		{"3-x", Range{}, false},
+		{"3-5-7", Range{}, false},
+		{" 3", Range{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRange(tt.s)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseRange(%q) returned %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseRange(%q) = %+v, want %+v", tt.s, got, tt.want)
+		}
+	}
+}
+
+// testTub creates a tub of n records in dir, each with its index as the
+// milliseconds and an image naming it.
+func testTub(t *testing.T, dir string, n int) *Tub {
+	tb, err := Create(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		if _, err := tb.Write(Record{Milliseconds: int64(i + 1)}, []byte(fmt.Sprint("image ", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tb
+}
+
+// checkTub checks that each record of tb has its image and there are no
+// other images, and returns the milliseconds of the records in order.
+func checkTub(t *testing.T, tb *Tub) []int64 {
+	records, err := tb.Records()
+	if err != nil {
+		t.Fatal(err)
+	}
+	images := make(map[string]bool)
+	var ms []int64
+	for _, r := range records {
+		data, err := ioutil.ReadFile(tb.ImagePath(r))
+		if err != nil {
+			t.Errorf("%s: record %d: %v", tb.Path, r.Index, err)
+		} else if want := fmt.Sprint("image ", r.Milliseconds-1); string(data) != want {
+			t.Errorf("%s: record %d has the image %q, want %q", tb.Path, r.Index, data, want)
+		}
+		images[r.Image] = true
+		ms = append(ms, r.Milliseconds)
+	}
+
+	files, err := ioutil.ReadDir(tb.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		if strings.HasSuffix(f.Name(), ".jpg") && !images[f.Name()] {
+			t.Errorf("%s: %s has no record", tb.Path, f.Name())
+		}
+	}
+	return ms
+}
+
+func TestDeleteIf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tb := testTub(t, filepath.Join(dir, "tub"), 10)
+
+	// overlapping ranges only delete each record once
+	ranges := []Range{{2, 5}, {4, 6}, {9, 9}, {20, 30}}
+	n, err := tb.DeleteIf(func(r Record) bool {
+		for _, rng := range ranges {
+			if rng.Contains(r.Index) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 6 {
+		t.Errorf("deleted %d records, want 6", n)
+	}
+	if got, want := checkTub(t, tb), []int64{1, 2, 8, 9}; !reflect.DeepEqual(got, want) {
+		t.Errorf("left records %v, want %v", got, want)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := testTub(t, filepath.Join(dir, "src"), 10)
+
+	for _, chunk := range []int{0, 1, 3, 20} {
+		train, err := Create(filepath.Join(dir, fmt.Sprint("train", chunk)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		val, err := Create(filepath.Join(dir, fmt.Sprint("val", chunk)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		nTrain, nVal, err := Split(src, train, val, 0.5, chunk, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trained, validated := checkTub(t, train), checkTub(t, val)
+		if nTrain != len(trained) || nVal != len(validated) {
+			t.Errorf("chunk %d: copied %d and %d records, the tubs have %d and %d", chunk, nTrain, nVal, len(trained), len(validated))
+		}
+
+		// each record is copied once, with its chunk
+		size := chunk
+		if size < 1 {
+			size = 1
+		}
+		in := make(map[int64]string)
+		for _, ms := range trained {
+			in[ms] = "train"
+		}
+		for _, ms := range validated {
+			if in[ms] != "" {
+				t.Errorf("chunk %d: record %d is in both tubs", chunk, ms)
+			}
+			in[ms] = "val"
+		}
+		for ms := int64(1); ms <= 10; ms++ {
+			if in[ms] == "" {
+				t.Errorf("chunk %d: record %d was not copied", chunk, ms)
+			}
+			if first := (ms-1)/int64(size)*int64(size) + 1; in[ms] != in[first] {
+				t.Errorf("chunk %d: record %d is in %s, the start of its chunk in %s", chunk, ms, in[ms], in[first])
+			}
+		}
+	}
+
+	if got := checkTub(t, src); len(got) != 10 {
+		t.Errorf("the source tub has %d records left", len(got))
+	}
+}
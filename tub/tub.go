@@ -0,0 +1,300 @@
+// Package tub reads and writes recorded driving data in the Donkeycar tub
+// format, so that the same data can be used with the Donkeycar tools.
+//
+// A tub is a directory holding a meta.json file, and for each record a
+// record_<index>.json file and the camera image <index>_cam-image_array_.jpg.
+package tub
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Keys of the values in a record.
+const (
+	KeyImage        = "cam/image_array"
+	KeyAngle        = "user/angle"
+	KeyThrottle     = "user/throttle"
+	KeyMode         = "user/mode"
+	KeyMilliseconds = "milliseconds"
+	KeySpeed        = "enc/speed"
+	KeyDistance     = "enc/distance"
+)
+
+// Meta describes the values saved in each record of a tub.
+type Meta struct {
+	Inputs []string `json:"inputs"`
+	Types  []string `json:"types"`
+}
+
+// DefaultMeta is the Meta of the tubs written by a car.
+var DefaultMeta = Meta{
+	Inputs: []string{KeyImage, KeyAngle, KeyThrottle, KeyMode, KeyMilliseconds},
+	Types:  []string{"image_array", "float", "float", "str", "int"},
+}
+
+// OdometryMeta is the Meta of the values added to the records by a car with
+// a wheel encoder.
+var OdometryMeta = Meta{
+	Inputs: []string{KeySpeed, KeyDistance},
+	Types:  []string{"float", "float"},
+}
+
+// Has returns true if the records have the value with key.
+func (m Meta) Has(key string) bool {
+	for _, in := range m.Inputs {
+		if in == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Record is one step of recorded driving.
+type Record struct {
+	// Index is the number of the record in the tub. It is taken from the
+	// file name and not saved in the record.
+	Index int `json:"-"`
+
+	Image        string  `json:"cam/image_array"`
+	Angle        float64 `json:"user/angle"`
+	Throttle     float64 `json:"user/throttle"`
+	Mode         string  `json:"user/mode"`
+	Milliseconds int64   `json:"milliseconds"`
+
+	// Speed in metres/second and Distance in metres since the car started
+	// are only saved if the Meta of the tub has them, as it does for a car
+	// with a wheel encoder.
+	Speed    float64 `json:"enc/speed,omitempty"`
+	Distance float64 `json:"enc/distance,omitempty"`
+}
+
+// odometryRecord is a Record that always has the speed and distance, even
+// when they are 0.
+type odometryRecord struct {
+	Record
+	Speed    float64 `json:"enc/speed"`
+	Distance float64 `json:"enc/distance"`
+}
+
+// Time returns the time the record was made.
+func (r Record) Time() time.Time {
+	return time.Unix(0, r.Milliseconds*int64(time.Millisecond))
+}
+
+// Tub is a directory of records.
+type Tub struct {
+	Path string
+	Meta Meta
+
+	mu   sync.Mutex
+	next int
+}
+
+// Create makes a new tub at path, or opens it to add more records if it
+// already exists.
+func Create(path string) (*Tub, error) {
+	if _, err := os.Stat(filepath.Join(path, "meta.json")); err == nil {
+		return Open(path)
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	t := &Tub{Path: path, Meta: DefaultMeta}
+	if err := t.saveMeta(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// AddInputs adds the inputs of m that the Meta of the tub does not have yet,
+// and saves it if any were added.
+func (t *Tub) AddInputs(m Meta) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	meta := Meta{
+		Inputs: append([]string(nil), t.Meta.Inputs...),
+		Types:  append([]string(nil), t.Meta.Types...),
+	}
+	for i, in := range m.Inputs {
+		if !meta.Has(in) {
+			meta.Inputs = append(meta.Inputs, in)
+			meta.Types = append(meta.Types, m.Types[i])
+		}
+	}
+	if len(meta.Inputs) == len(t.Meta.Inputs) {
+		return nil
+	}
+	t.Meta = meta
+	return t.saveMeta()
+}
+
+func (t *Tub) saveMeta() error {
+	data, err := json.Marshal(t.Meta)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(t.Path, "meta.json"), data, 0644)
+}
+
+// Open opens an existing tub.
+func Open(path string) (*Tub, error) {
+	data, err := ioutil.ReadFile(filepath.Join(path, "meta.json"))
+	if err != nil {
+		return nil, err
+	}
+	t := &Tub{Path: path}
+	if err := json.Unmarshal(data, &t.Meta); err != nil {
+		return nil, fmt.Errorf("tub %s: %v", path, err)
+	}
+
+	indexes, err := t.Indexes()
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) > 0 {
+		t.next = indexes[len(indexes)-1] + 1
+	}
+	return t, nil
+}
+
+// NewPath returns a path in dir for a new tub, named like the Donkeycar tubs
+// with a number and the date, such as tub_3_18-08-25.
+func NewPath(dir string) string {
+	n := 1
+	matches, _ := filepath.Glob(filepath.Join(dir, "tub_*"))
+	for _, m := range matches {
+		parts := strings.Split(filepath.Base(m), "_")
+		if len(parts) < 2 {
+			continue
+		}
+		if i, err := strconv.Atoi(parts[1]); err == nil && i >= n {
+			n = i + 1
+		}
+	}
+	return filepath.Join(dir, fmt.Sprintf("tub_%d_%s", n, time.Now().Format("06-01-02")))
+}
+
+// Indexes returns the index of each record in the tub, in order.
+func (t *Tub) Indexes() ([]int, error) {
+	files, err := ioutil.ReadDir(t.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []int
+	for _, f := range files {
+		name := f.Name()
+		if !strings.HasPrefix(name, "record_") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		i, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "record_"), ".json"))
+		if err != nil {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+// Records reads all of the records in the tub, in order.
+func (t *Tub) Records() ([]Record, error) {
+	indexes, err := t.Indexes()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(indexes))
+	for _, i := range indexes {
+		r, err := t.Read(i)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// Read reads the record with index i.
+func (t *Tub) Read(i int) (Record, error) {
+	r := Record{Index: i}
+	data, err := ioutil.ReadFile(t.recordPath(i))
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return r, fmt.Errorf("record %d: %v", i, err)
+	}
+	return r, nil
+}
+
+// Write adds a record with its JPEG image to the tub, and returns the record
+// with its Index and Image set.
+func (t *Tub) Write(r Record, jpeg []byte) (Record, error) {
+	t.mu.Lock()
+	r.Index = t.next
+	t.next++
+	t.mu.Unlock()
+
+	r.Image = fmt.Sprintf("%d_cam-image_array_.jpg", r.Index)
+	if r.Milliseconds == 0 {
+		r.Milliseconds = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+
+	if err := ioutil.WriteFile(t.ImagePath(r), jpeg, 0644); err != nil {
+		return r, err
+	}
+	return r, t.Save(r)
+}
+
+// Save writes the record file of r, replacing it if it exists.
+func (t *Tub) Save(r Record) error {
+	t.mu.Lock()
+	odometry := t.Meta.Has(KeySpeed)
+	t.mu.Unlock()
+
+	var v interface{} = r
+	if odometry {
+		v = odometryRecord{Record: r, Speed: r.Speed, Distance: r.Distance}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(t.recordPath(r.Index), data, 0644)
+}
+
+// Delete removes the record with index i and its image.
+func (t *Tub) Delete(i int) error {
+	r, err := t.Read(i)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(t.recordPath(i)); err != nil {
+		return err
+	}
+	if err := os.Remove(t.ImagePath(r)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ImagePath returns the path of the image of r.
+func (t *Tub) ImagePath(r Record) string {
+	return filepath.Join(t.Path, r.Image)
+}
+
+func (t *Tub) recordPath(i int) string {
+	return filepath.Join(t.Path, fmt.Sprintf("record_%d.json", i))
+}
@@ -0,0 +1,70 @@
+package tub
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveOdometry(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name     string
+		meta     bool
+		record   Record
+		keys     bool
+		speed    float64
+		distance float64
+	}{
+		{"no encoder", false, Record{Angle: 0.1}, false, 0, 0},
+		{"standing still", true, Record{Angle: 0.1}, true, 0, 0},
+		{"moving", true, Record{Speed: 1.5, Distance: 12}, true, 1.5, 12},
+	}
+	for _, tt := range tests {
+		tb, err := Create(filepath.Join(dir, tt.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.meta {
+			if err := tb.AddInputs(OdometryMeta); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r, err := tb.Write(tt.record, []byte("jpeg"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ioutil.ReadFile(tb.recordPath(r.Index))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var values map[string]interface{}
+		if err := json.Unmarshal(data, &values); err != nil {
+			t.Fatal(err)
+		}
+		_, speed := values[KeySpeed]
+		_, distance := values[KeyDistance]
+		if speed != tt.keys || distance != tt.keys {
+			t.Errorf("%s: saved %s", tt.name, data)
+		}
+		if _, ok := values[KeyMode]; !ok {
+			t.Errorf("%s: the mode is missing from %s", tt.name, data)
+		}
+
+		read, err := tb.Read(r.Index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read.Speed != tt.speed || read.Distance != tt.distance || read.Angle != tt.record.Angle {
+			t.Errorf("%s: read back %+v", tt.name, read)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package vision
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"gocv.io/x/gocv"
+)
+
+// DrawComparison draws the recorded steering and throttle in green and the
+// pilot's in red over a frame: needles from the bottom centre for the
+// steering, and bars on the right for the throttle. A warning is shown if the
+// pilot could not decide.
+func DrawComparison(img *gocv.Mat, angle, throttle, steering, pilotThrottle float64, ok bool) {
+	dim := img.Size()
+	if len(dim) < 2 {
+		return
+	}
+	height, width := dim[0], dim[1]
+
+	base := image.Pt(width/2, height-hudMargin)
+	length := float64(height) / 3
+	drawNeedle(img, base, length, angle, hudGreen)
+	drawNeedle(img, base, length, steering, hudRed)
+
+	w := 10
+	h := height * 2 / 5
+	top := (height - h) / 2
+	mid := height / 2
+	for i, t := range []float64{throttle, pilotThrottle} {
+		c := hudGreen
+		if i == 1 {
+			c = hudRed
+		}
+		left := width - hudMargin - (2-i)*(w+2)
+		fill := int(clamp(t, -1, 1) * float64(h/2))
+		gocv.Rectangle(img, image.Rect(left, mid, left+w, mid-fill).Canon(), c, -1)
+		gocv.Rectangle(img, image.Rect(left, top, left+w, top+h), hudWhite, 1)
+	}
+
+	gocv.PutText(img, fmt.Sprintf("user  %+.2f %+.2f", angle, throttle), image.Pt(hudMargin, hudMargin+12), hudFont, hudScale, hudGreen, 1)
+	gocv.PutText(img, fmt.Sprintf("pilot %+.2f %+.2f", steering, pilotThrottle), image.Pt(hudMargin, hudMargin+30), hudFont, hudScale, hudRed, 1)
+	if !ok {
+		drawWarning(img, "PILOT LOST", width, height)
+	}
+}
+
+// drawNeedle draws a line from base pointing up, turned by steering from
+// -1 (45 degrees left) to 1 (45 degrees right).
+func drawNeedle(img *gocv.Mat, base image.Point, length, steering float64, c color.RGBA) {
+	a := clamp(steering, -1, 1) * math.Pi / 4
+	tip := image.Pt(base.X+int(length*math.Sin(a)), base.Y-int(length*math.Cos(a)))
+	gocv.Line(img, base, tip, c, 2)
+}
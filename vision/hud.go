@@ -0,0 +1,122 @@
+package vision
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/telemetry"
+)
+
+var (
+	hudWhite  = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	hudGreen  = color.RGBA{G: 255, A: 255}
+	hudYellow = color.RGBA{R: 255, G: 255, A: 255}
+	hudRed    = color.RGBA{R: 255, A: 255}
+)
+
+const (
+	hudFont   = gocv.FontHersheySimplex
+	hudScale  = 0.5
+	hudMargin = 8
+)
+
+// DrawHUD draws a heads up display of the telemetry over a camera frame:
+// steering and throttle gauges, the drive mode, frame rate, loop latency and
+// speed, a recording indicator, and warnings when the line is lost, the car
+// has crashed or it is stopping for an obstacle or an object.
+func DrawHUD(img *gocv.Mat, d telemetry.Data) {
+	dim := img.Size()
+	if len(dim) < 2 {
+		return
+	}
+	height, width := dim[0], dim[1]
+
+	// status line
+	status := fmt.Sprintf("%s  %.1f fps  %.0f ms", d.Mode, d.FPS, d.LatencyMS)
+	switch {
+	case d.TargetSpeed > 0:
+		status += fmt.Sprintf("  %.2f/%.2f m/s", d.Speed, d.TargetSpeed)
+	case d.Odometer > 0:
+		status += fmt.Sprintf("  %.2f m/s", d.Speed)
+	}
+	gocv.PutText(img, status, image.Pt(hudMargin, hudMargin+12), hudFont, hudScale, hudWhite, 1)
+
+	if d.Recording {
+		center := image.Pt(width-hudMargin-8, hudMargin+8)
+		gocv.Circle(img, center, 6, hudRed, -1)
+		gocv.PutText(img, fmt.Sprintf("REC %d", d.Records), image.Pt(center.X-90, center.Y+5), hudFont, hudScale, hudRed, 1)
+	}
+
+	drawSteeringGauge(img, d.Steering, width, height)
+	drawThrottleGauge(img, d.Throttle, width, height)
+
+	switch {
+	case d.Crash != nil:
+		drawWarning(img, "CRASH: "+string(d.Crash.Kind), width, height)
+	case len(d.FailedDevices) > 0:
+		drawWarning(img, "I2C FAULT: "+strings.Join(d.FailedDevices, ","), width, height)
+	case d.Obstacle:
+		drawWarning(img, fmt.Sprintf("OBSTACLE %.2fm", d.Range), width, height)
+	case !d.LineFound:
+		drawWarning(img, "LINE LOST", width, height)
+	case d.Behaviour != "":
+		drawWarning(img, strings.ToUpper(d.Behaviour), width, height)
+	}
+}
+
+// drawSteeringGauge draws a horizontal bar along the bottom of the frame,
+// filled out from the centre towards the steering direction.
+func drawSteeringGauge(img *gocv.Mat, steering float64, width, height int) {
+	w := width * 2 / 5
+	h := 10
+	left := (width - w) / 2
+	top := height - hudMargin - h
+	mid := width / 2
+
+	fill := int(clamp(steering, -1, 1) * float64(w/2))
+	fillRect := image.Rect(mid, top, mid+fill, top+h).Canon()
+	gocv.Rectangle(img, fillRect, hudGreen, -1)
+	gocv.Rectangle(img, image.Rect(left, top, left+w, top+h), hudWhite, 1)
+	gocv.Line(img, image.Pt(mid, top-3), image.Pt(mid, top+h+3), hudWhite, 1)
+}
+
+// drawThrottleGauge draws a vertical bar along the right side of the frame,
+// filled up from the centre when going forward and down when reversing.
+func drawThrottleGauge(img *gocv.Mat, throttle float64, width, height int) {
+	w := 10
+	h := height * 2 / 5
+	left := width - hudMargin - w
+	top := (height - h) / 2
+	mid := height / 2
+
+	c := hudGreen
+	if throttle < 0 {
+		c = hudYellow
+	}
+	fill := int(clamp(throttle, -1, 1) * float64(h/2))
+	fillRect := image.Rect(left, mid, left+w, mid-fill).Canon()
+	gocv.Rectangle(img, fillRect, c, -1)
+	gocv.Rectangle(img, image.Rect(left, top, left+w, top+h), hudWhite, 1)
+	gocv.Line(img, image.Pt(left-3, mid), image.Pt(left+w+3, mid), hudWhite, 1)
+}
+
+// drawWarning draws a message in the middle of the frame.
+func drawWarning(img *gocv.Mat, msg string, width, height int) {
+	size := gocv.GetTextSize(msg, hudFont, hudScale*2, 2)
+	org := image.Pt((width-size.X)/2, (height+size.Y)/2)
+	gocv.PutText(img, msg, org, hudFont, hudScale*2, hudRed, 2)
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,72 @@
+//go:build matprofile
+// +build matprofile
+
+package vision
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/telemetry"
+)
+
+// TestNoMatLeak runs the line follower over thousands of synthetic frames, the
+// same way as the car including the HUD and the stage streams, and checks that
+// no Mats are left over. gocv only counts Mats with the matprofile build tag.
+func TestNoMatLeak(t *testing.T) {
+	frames := 5000
+	if testing.Short() {
+		frames = 500
+	}
+	const width, height = 640, 480
+
+	before := gocv.MatProfile.Count()
+
+	follower := NewLineFollower()
+	stages := NewStageStreams()
+	frame := gocv.NewMatWithSize(height, width, gocv.MatTypeCV8UC3)
+
+	found := 0
+	for i := 0; i < frames; i++ {
+		drawLeakFrame(&frame, i, width, height)
+
+		steering, ok := follower.Process(frame, stages.Add)
+		if ok {
+			found++
+		}
+
+		annotated := follower.Annotated()
+		DrawHUD(&annotated, telemetry.Data{Steering: steering, LineFound: ok})
+		stages.Finish(annotated)
+	}
+
+	frame.Close()
+	stages.Close()
+	follower.Close()
+
+	if found == 0 {
+		t.Errorf("line not found in any of %d frames", frames)
+	}
+	if after := gocv.MatProfile.Count(); after != before {
+		var profile strings.Builder
+		gocv.MatProfile.WriteTo(&profile, 1)
+		t.Errorf("%d Mats leaked over %d frames:\n%s", after-before, frames, profile.String())
+	}
+}
+
+// drawLeakFrame draws a dark track with a white line that sweeps from side to
+// side. Every 100th frame has no line at all, to cover the lost line path.
+func drawLeakFrame(img *gocv.Mat, i, width, height int) {
+	img.SetTo(gocv.NewScalar(40, 40, 40, 0))
+	if i%100 == 99 {
+		return
+	}
+
+	x := width/2 + int(float64(width/3)*math.Sin(float64(i)/50))
+	gocv.Line(img, image.Pt(x, height), image.Pt(width/2, height/3), color.RGBA{R: 255, G: 255, B: 255, A: 255}, 30)
+}
@@ -0,0 +1,247 @@
+package vision
+
+import (
+	"sync"
+	"time"
+
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/metrics"
+	"github.com/hybridgroup/gophercar/telemetry"
+)
+
+// FrameSource is where a Pipeline reads frames from, such as a *gocv.VideoCapture.
+type FrameSource interface {
+	Read(m *gocv.Mat) bool
+}
+
+// Pipeline reads frames, runs the vision on them and encodes the results for
+// streaming, each in its own goroutine, so that a slow stream encoder cannot
+// hold up steering.
+//
+// Frames are handed from one stage to the next with latest frame wins: if a
+// stage is still busy when a new frame arrives, the frame it has not started
+// on yet is dropped and counted.
+type Pipeline struct {
+	Source FrameSource
+
+	// Process is called by the vision worker with each new frame and the time
+	// it was read, and returns the annotated frame to stream. The frame
+	// belongs to the Pipeline and must not be kept. The returned Mat belongs
+	// to the caller, and is copied before Process is called again.
+	Process func(img gocv.Mat, captured time.Time) gocv.Mat
+
+	// Stream is called by the encoder with the latest annotated frame, along
+	// with the frame as it was captured if Raw is set.
+	Stream func(annotated, raw gocv.Mat)
+
+	// StreamInterval is the shortest time between frames passed to Stream.
+	StreamInterval time.Duration
+
+	// Raw keeps a copy of each streamed frame from before it was processed.
+	Raw bool
+
+	// Timings, if set, records how long the capture, vision and encoding
+	// stages take.
+	Timings *metrics.Timings
+
+	mu     sync.Mutex
+	frames telemetry.Frames
+}
+
+// NewPipeline returns a new Pipeline that streams at 10 frames per second.
+func NewPipeline(src FrameSource, process func(gocv.Mat, time.Time) gocv.Mat, stream func(annotated, raw gocv.Mat)) *Pipeline {
+	return &Pipeline{
+		Source:         src,
+		Process:        process,
+		Stream:         stream,
+		StreamInterval: 100 * time.Millisecond,
+	}
+}
+
+// Run starts the pipeline and blocks until the Source has no more frames.
+func (p *Pipeline) Run() {
+	captured := newSlot()
+	defer captured.free()
+	annotated := newSlot()
+	defer annotated.free()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		p.processFrames(captured, annotated)
+	}()
+	go func() {
+		defer wg.Done()
+		p.streamFrames(annotated)
+	}()
+
+	p.captureFrames(captured)
+	wg.Wait()
+}
+
+// Frames returns the frame counters.
+func (p *Pipeline) Frames() telemetry.Frames {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.frames
+}
+
+func (p *Pipeline) captureFrames(out *slot) {
+	f := newFrame()
+	defer f.free()
+	defer out.close()
+	timer := p.Timings.Timer(metrics.StageCapture)
+
+	for {
+		start := time.Now()
+		if ok := p.Source.Read(&f.img); !ok {
+			return
+		}
+		if f.img.Empty() {
+			continue
+		}
+
+		f.captured = time.Now()
+		timer.Since(start)
+		dropped := out.put(&f)
+
+		p.mu.Lock()
+		p.frames.Captured++
+		if dropped {
+			p.frames.Dropped++
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *Pipeline) processFrames(in, out *slot) {
+	f := newFrame()
+	defer f.free()
+	pub := newFrame()
+	defer pub.free()
+	defer out.close()
+	timer := p.Timings.Timer(metrics.StageVision)
+
+	var lastStream time.Time
+	for in.take(&f) {
+		start := time.Now()
+
+		due := p.Stream != nil && start.Sub(lastStream) >= p.StreamInterval
+		if due && p.Raw {
+			f.img.CopyTo(&pub.raw)
+		}
+
+		result := p.Process(f.img, f.captured)
+		timer.Since(start)
+
+		dropped := false
+		if due {
+			result.CopyTo(&pub.img)
+			pub.captured = f.captured
+			dropped = out.put(&pub)
+			lastStream = start
+		}
+
+		p.mu.Lock()
+		p.frames.Processed++
+		if dropped {
+			p.frames.StreamDropped++
+		}
+		p.mu.Unlock()
+	}
+}
+
+func (p *Pipeline) streamFrames(in *slot) {
+	f := newFrame()
+	defer f.free()
+	timer := p.Timings.Timer(metrics.StageEncoding)
+
+	for in.take(&f) {
+		start := time.Now()
+		p.Stream(f.img, f.raw)
+		timer.Since(start)
+
+		p.mu.Lock()
+		p.frames.Streamed++
+		p.mu.Unlock()
+	}
+}
+
+// frame is an image passed between the stages of the Pipeline, along with an
+// optional copy of it from before it was processed.
+type frame struct {
+	img      gocv.Mat
+	raw      gocv.Mat
+	captured time.Time
+}
+
+func newFrame() frame {
+	return frame{img: gocv.NewMat(), raw: gocv.NewMat()}
+}
+
+func (f *frame) free() {
+	f.img.Close()
+	f.raw.Close()
+}
+
+// slot hands the latest frame from one goroutine to another by swapping Mats,
+// so no image data is copied.
+type slot struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	f      frame
+	full   bool
+	closed bool
+}
+
+func newSlot() *slot {
+	s := &slot{f: newFrame()}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// put swaps f into the slot. It returns true if this replaced a frame that was
+// never taken.
+func (s *slot) put(f *frame) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	*f, s.f = s.f, *f
+	dropped := s.full
+	s.full = true
+	s.cond.Signal()
+	return dropped
+}
+
+// take waits for a frame and swaps it into f. It returns false once the slot
+// has been closed and there are no more frames.
+func (s *slot) take(f *frame) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for !s.full && !s.closed {
+		s.cond.Wait()
+	}
+	if !s.full {
+		return false
+	}
+
+	*f, s.f = s.f, *f
+	s.full = false
+	return true
+}
+
+// close tells the taker that no more frames are coming.
+func (s *slot) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	s.cond.Broadcast()
+}
+
+func (s *slot) free() {
+	s.f.free()
+}
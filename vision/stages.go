@@ -0,0 +1,119 @@
+package vision
+
+import (
+	"image"
+	"net/http"
+
+	"github.com/hybridgroup/mjpeg"
+	"gocv.io/x/gocv"
+)
+
+// GridStage is the name of the stream that shows all of the stages at once.
+const GridStage = "grid"
+
+// gridColumns is the number of stages side by side in the grid.
+const gridColumns = 3
+
+// StageStreams serves each intermediate image of Process as its own MJPEG
+// stream, along with a grid that shows all of them and the final result.
+//
+// Pass its Add method to Process, then call Finish with the annotated frame
+// once Process has returned.
+type StageStreams struct {
+	streams map[string]*mjpeg.Stream
+
+	grid  gocv.Mat
+	cell  gocv.Mat
+	color gocv.Mat
+	cellW int
+	cellH int
+	index int
+}
+
+// NewStageStreams returns a new StageStreams.
+func NewStageStreams() *StageStreams {
+	s := &StageStreams{
+		streams: make(map[string]*mjpeg.Stream),
+		grid:    gocv.NewMat(),
+		cell:    gocv.NewMat(),
+		color:   gocv.NewMat(),
+	}
+	for _, name := range Stages {
+		s.streams[name] = mjpeg.NewStream()
+	}
+	s.streams[GridStage] = mjpeg.NewStream()
+	return s
+}
+
+// Handle registers a handler for each stage at prefix/<stage>, for example
+// /stage/threshold, along with prefix/grid.
+func (s *StageStreams) Handle(mux *http.ServeMux, prefix string) {
+	for name, stream := range s.streams {
+		mux.Handle(prefix+"/"+name, stream)
+	}
+}
+
+// Add encodes the image of a stage into its stream and into the grid. It has
+// the signature of a StageFunc.
+func (s *StageStreams) Add(name string, img gocv.Mat) {
+	if stream, ok := s.streams[name]; ok {
+		buf, _ := gocv.IMEncode(".jpg", img)
+		stream.UpdateJPEG(buf)
+	}
+
+	s.addCell(name, img)
+}
+
+// Finish adds the final annotated frame to the grid, then encodes the grid
+// into its stream and starts a new one for the next frame.
+func (s *StageStreams) Finish(result gocv.Mat) {
+	s.addCell("result", result)
+	if !s.grid.Empty() {
+		buf, _ := gocv.IMEncode(".jpg", s.grid)
+		s.streams[GridStage].UpdateJPEG(buf)
+	}
+	s.index = 0
+}
+
+// Close frees the Mats used for the grid.
+func (s *StageStreams) Close() error {
+	s.grid.Close()
+	s.cell.Close()
+	s.color.Close()
+	return nil
+}
+
+// addCell draws a half size copy of img into the next cell of the grid.
+func (s *StageStreams) addCell(name string, img gocv.Mat) {
+	if img.Empty() {
+		return
+	}
+
+	// size the grid from the first stage of each frame
+	if s.index == 0 {
+		w, h := img.Cols()/2, img.Rows()/2
+		rows := (len(Stages) + 1 + gridColumns - 1) / gridColumns
+		if s.grid.Empty() || w != s.cellW || h != s.cellH {
+			s.grid.Close()
+			s.grid = gocv.NewMatWithSize(h*rows, w*gridColumns, gocv.MatTypeCV8UC3)
+			s.cellW, s.cellH = w, h
+		}
+		s.grid.SetTo(gocv.NewScalar(0, 0, 0, 0))
+	}
+
+	if img.Channels() == 1 {
+		gocv.CvtColor(img, &s.color, gocv.ColorGrayToBGR)
+	} else {
+		img.CopyTo(&s.color)
+	}
+	gocv.Resize(s.color, &s.cell, image.Pt(s.cellW, s.cellH), 0, 0, gocv.InterpolationLinear)
+	gocv.PutText(&s.cell, name, image.Pt(hudMargin, hudMargin+12), hudFont, hudScale, hudYellow, 1)
+
+	x := (s.index % gridColumns) * s.cellW
+	y := (s.index / gridColumns) * s.cellH
+	region := s.grid.Region(image.Rect(x, y, x+s.cellW, y+s.cellH))
+	s.cell.CopyTo(&region)
+	region.Close()
+
+	s.index++
+}
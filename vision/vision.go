@@ -0,0 +1,157 @@
+// Package vision finds the track in the camera frames and draws the results,
+// so that they can be watched over the MJPEG stream.
+package vision
+
+import (
+	"image"
+	"image/color"
+
+	"gocv.io/x/gocv"
+)
+
+// Names of the intermediate images of LineFollower.Process, in the order they
+// are created.
+const (
+	StageGray      = "gray"
+	StageBlurred   = "blurred"
+	StageThreshold = "threshold"
+	StageEroded    = "eroded"
+	StageDilated   = "dilated"
+)
+
+// DefaultCropTop is the fraction of the frame at the top that the line
+// follower ignores.
+const DefaultCropTop = 0.4
+
+// Stages lists all of the intermediate images of LineFollower.Process.
+var Stages = []string{StageGray, StageBlurred, StageThreshold, StageEroded, StageDilated}
+
+// StageFunc is called with the name and the image of each stage of
+// LineFollower.Process. The image is only valid until the function returns.
+type StageFunc func(name string, img gocv.Mat)
+
+// LineFollower finds the brightest large shape in the bottom part of each
+// frame, which is expected to be the line marking the track, and works out
+// the steering needed to keep it in the centre.
+//
+// All of the Mats it needs are allocated once and reused for every frame, so
+// call Close when done with it.
+type LineFollower struct {
+	// CropTop is the fraction of the frame at the top that is ignored, as it
+	// mostly shows what is beyond the track.
+	CropTop float64
+
+	// Threshold is the grey level from 0 to 255 above which a pixel is
+	// considered to be part of the line.
+	Threshold float32
+
+	gray      gocv.Mat
+	blurred   gocv.Mat
+	threshold gocv.Mat
+	eroded    gocv.Mat
+	dilated   gocv.Mat
+	line      gocv.Mat
+	kernel    gocv.Mat
+	annotated gocv.Mat
+}
+
+// NewLineFollower returns a new LineFollower with the settings used at
+// Gophercon 2018.
+func NewLineFollower() *LineFollower {
+	return &LineFollower{
+		CropTop:   DefaultCropTop,
+		Threshold: 100,
+		gray:      gocv.NewMat(),
+		blurred:   gocv.NewMat(),
+		threshold: gocv.NewMat(),
+		eroded:    gocv.NewMat(),
+		dilated:   gocv.NewMat(),
+		line:      gocv.NewMat(),
+		kernel:    gocv.GetStructuringElement(gocv.MorphRect, image.Point{X: 6, Y: 6}),
+		annotated: gocv.NewMat(),
+	}
+}
+
+// Close frees all of the Mats used by the LineFollower.
+func (lf *LineFollower) Close() error {
+	for _, m := range []*gocv.Mat{&lf.gray, &lf.blurred, &lf.threshold, &lf.eroded, &lf.dilated, &lf.line, &lf.kernel, &lf.annotated} {
+		m.Close()
+	}
+	return nil
+}
+
+// Process looks for the line in a frame and returns the correct steering
+// direction to keep the car on the track. If no line can be found the second
+// return value is false, and the steering should not be used.
+//
+// The frame is not modified. A copy of the part of it that was searched, with
+// the analysis results drawn over it, is available from Annotated afterwards.
+//
+// If stage is not nil it is called with each of the intermediate images, see Stages.
+func (lf *LineFollower) Process(frame gocv.Mat, stage StageFunc) (float64, bool) {
+	dim := frame.Size()
+	cropHeight := int(float64(dim[0]) * lf.CropTop)
+	region := frame.Region(image.Rectangle{image.Point{0, cropHeight}, image.Point{dim[1], dim[0]}})
+	region.CopyTo(&lf.annotated)
+	region.Close()
+
+	gocv.CvtColor(lf.annotated, &lf.gray, gocv.ColorBGRToGray)
+	gocv.GaussianBlur(lf.gray, &lf.blurred, image.Point{X: 5, Y: 5}, float64(5), float64(5), gocv.BorderDefault)
+	gocv.Threshold(lf.blurred, &lf.threshold, lf.Threshold, float32(255), gocv.ThresholdBinary)
+
+	gocv.Erode(lf.threshold, &lf.eroded, lf.kernel)
+	gocv.Dilate(lf.eroded, &lf.dilated, lf.kernel)
+
+	if stage != nil {
+		stage(StageGray, lf.gray)
+		stage(StageBlurred, lf.blurred)
+		stage(StageThreshold, lf.threshold)
+		stage(StageEroded, lf.eroded)
+		stage(StageDilated, lf.dilated)
+	}
+
+	contours := gocv.FindContours(lf.dilated, gocv.RetrievalList, gocv.ChainApproxNone)
+	if len(contours) == 0 {
+		return 0, false
+	}
+	maxArea := float64(0)
+	maxContour := 0
+
+	for idx, contour := range contours {
+		area := gocv.ContourArea(contour)
+		if area > maxArea {
+			maxArea = area
+			maxContour = idx
+		}
+	}
+
+	if lf.line.Rows() != lf.annotated.Rows() || lf.line.Cols() != lf.annotated.Cols() {
+		lf.line.Close()
+		lf.line = gocv.NewMatWithSize(lf.annotated.Rows(), lf.annotated.Cols(), gocv.MatTypeCV8U)
+	}
+	lf.line.SetTo(gocv.NewScalar(0, 0, 0, 0))
+	gocv.FillPoly(&lf.line, contours[maxContour:maxContour+1], color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	M := gocv.Moments(lf.line, true)
+	if M["m00"] == 0 {
+		return 0, false
+	}
+
+	cx := M["m10"] / M["m00"]
+
+	gocv.DrawContours(&lf.annotated, contours, maxContour, color.RGBA{R: 255, A: 255}, 3)
+	dim = lf.annotated.Size()
+	centerX := dim[1] / 2
+	gocv.Line(&lf.annotated, image.Point{X: centerX, Y: 0}, image.Point{X: centerX, Y: dim[0]}, color.RGBA{B: 255, A: 255}, 1)
+	gocv.Circle(&lf.annotated, image.Point{X: int(cx), Y: dim[0] / 2}, 1, color.RGBA{G: 255, A: 255}, 2)
+
+	steer := cx/float64(centerX) - 0.5
+
+	return steer, true
+}
+
+// Annotated returns the searched part of the last frame given to Process, with
+// the results drawn over it. It is owned by the LineFollower and is reused by
+// the next call to Process, so it must not be closed.
+func (lf *LineFollower) Annotated() gocv.Mat {
+	return lf.annotated
+}
@@ -0,0 +1,31 @@
+package vision_test
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/hybridgroup/gophercar/vision/visiontest"
+)
+
+var update = flag.Bool("update", false, "write the golden images in testdata/golden from the output of the line follower")
+
+// TestLineFollower checks the line follower against the frames in testdata,
+// the same as gophercar test-vision.
+func TestLineFollower(t *testing.T) {
+	cases, err := visiontest.Load("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := visiontest.Run("testdata", cases, visiontest.DefaultTolerance, *update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(cases) {
+		t.Fatalf("got %d results for %d cases", len(results), len(cases))
+	}
+	for _, r := range results {
+		if !r.OK() {
+			t.Errorf("%s (%s): %v", r.Case.Name, r.Case.Note, r.Failures)
+		}
+	}
+}
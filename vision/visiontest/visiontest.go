@@ -0,0 +1,146 @@
+// Package visiontest checks the line follower against a set of camera frames
+// with known answers, so that a change to the vision can be reviewed for
+// regressions.
+//
+// A set is a directory with a cases.json listing the frames, the range the
+// steering must be in for each and whether the line should be found. For each
+// case the annotated output of the line follower is also compared with a
+// golden image in golden/<name>.png, which is written by Update so that
+// changes to it show up in review.
+package visiontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gocv.io/x/gocv"
+
+	"github.com/hybridgroup/gophercar/vision"
+)
+
+// Case is a frame with the expected result of the line follower.
+type Case struct {
+	Name  string `json:"name"`
+	Frame string `json:"frame"`
+	Note  string `json:"note,omitempty"`
+
+	// Found is true if the line should be found, in which case the steering
+	// offset must be between Min and Max.
+	Found bool    `json:"found"`
+	Min   float64 `json:"min,omitempty"`
+	Max   float64 `json:"max,omitempty"`
+}
+
+// Result is what the line follower made of a Case.
+type Result struct {
+	Case     Case
+	Steering float64
+	Found    bool
+
+	// Diff is the mean difference in grey levels from the golden image, or
+	// -1 if there is none.
+	Diff float64
+
+	// Failures lists what was wrong.
+	Failures []string
+}
+
+// OK returns true if the case passed.
+func (r Result) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// DefaultTolerance is the largest mean difference in grey levels from the
+// golden image that passes.
+const DefaultTolerance = 1.0
+
+// Load reads the cases of the set in dir.
+func Load(dir string) ([]Case, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cases.json"))
+	if err != nil {
+		return nil, err
+	}
+	var cases []Case
+	if err := json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("%s: %v", dir, err)
+	}
+	return cases, nil
+}
+
+// Run runs the line follower over each case of the set in dir. If update is
+// true, the golden images are written from the output instead of being
+// compared with it.
+func Run(dir string, cases []Case, tolerance float64, update bool) ([]Result, error) {
+	lf := vision.NewLineFollower()
+	defer lf.Close()
+
+	if update {
+		if err := os.MkdirAll(filepath.Join(dir, "golden"), 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []Result
+	for _, c := range cases {
+		img := gocv.IMRead(filepath.Join(dir, c.Frame), gocv.IMReadColor)
+		if img.Empty() {
+			img.Close()
+			return nil, fmt.Errorf("case %s: reading %s", c.Name, c.Frame)
+		}
+		steering, found := lf.Process(img, nil)
+		img.Close()
+
+		r := Result{Case: c, Steering: steering, Found: found, Diff: -1}
+		switch {
+		case found != c.Found:
+			r.fail("line found is %v, expected %v", found, c.Found)
+		case found && (steering < c.Min || steering > c.Max):
+			r.fail("steering %.3f is outside %.3f to %.3f", steering, c.Min, c.Max)
+		}
+
+		golden := filepath.Join(dir, "golden", c.Name+".png")
+		if update {
+			if !gocv.IMWrite(golden, lf.Annotated()) {
+				return nil, fmt.Errorf("case %s: writing %s", c.Name, golden)
+			}
+		} else if err := r.compare(lf.Annotated(), golden, tolerance); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// compare compares the annotated output with the golden image, if there is
+// one.
+func (r *Result) compare(annotated gocv.Mat, path string, tolerance float64) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	golden := gocv.IMRead(path, gocv.IMReadColor)
+	defer golden.Close()
+	if golden.Empty() {
+		return fmt.Errorf("case %s: reading %s", r.Case.Name, path)
+	}
+	if golden.Rows() != annotated.Rows() || golden.Cols() != annotated.Cols() {
+		r.fail("output is %dx%d, golden image is %dx%d", annotated.Cols(), annotated.Rows(), golden.Cols(), golden.Rows())
+		return nil
+	}
+
+	diff := gocv.NewMat()
+	defer diff.Close()
+	gocv.AbsDiff(annotated, golden, &diff)
+	mean := diff.Mean()
+	r.Diff = (mean.Val1 + mean.Val2 + mean.Val3) / 3
+	if r.Diff > tolerance {
+		r.fail("output differs from the golden image by %.2f", r.Diff)
+	}
+	return nil
+}
+
+func (r *Result) fail(format string, args ...interface{}) {
+	r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+}